@@ -43,6 +43,15 @@ func (b *Block) ReadFrom(r io.Reader) (int64, error) {
 	return read, nil
 }
 
+// Size returns the size of the encoded block
+func (b *Block) Size() (int64, error) {
+	var buffer bytes.Buffer
+	if _, err := b.WriteTo(&buffer); err != nil {
+		return 0, err
+	}
+	return int64(buffer.Len()), nil
+}
+
 // MarshalJSON is a marshaler function
 func (b *Block) MarshalJSON() ([]byte, error) {
 	var buffer bytes.Buffer
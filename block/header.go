@@ -11,12 +11,18 @@ import (
 	"github.com/fletaio/framework/chain"
 )
 
+// StateRootHashVersion is the first version of the header that has the state root hash
+// The state root hash is not encoded in headers of previous versions to keep hashes of them
+const StateRootHashVersion = 2
+
 // Header is validation informations
+// StateRootHash is used only when the version of the header is StateRootHashVersion or later
 type Header struct {
 	chain.Base
 	ChainCoord    common.Coordinate
 	LevelRootHash hash.Hash256
 	ContextHash   hash.Hash256
+	StateRootHash hash.Hash256
 	Formulator    common.Address
 	TimeoutCount  uint32
 }
@@ -49,6 +55,13 @@ func (bh *Header) WriteTo(w io.Writer) (int64, error) {
 	} else {
 		wrote += n
 	}
+	if bh.Version() >= StateRootHashVersion {
+		if n, err := bh.StateRootHash.WriteTo(w); err != nil {
+			return wrote, err
+		} else {
+			wrote += n
+		}
+	}
 	if n, err := bh.Formulator.WriteTo(w); err != nil {
 		return wrote, err
 	} else {
@@ -85,6 +98,13 @@ func (bh *Header) ReadFrom(r io.Reader) (int64, error) {
 	} else {
 		read += n
 	}
+	if bh.Version() >= StateRootHashVersion {
+		if n, err := bh.StateRootHash.ReadFrom(r); err != nil {
+			return read, err
+		} else {
+			read += n
+		}
+	}
 	if n, err := bh.Formulator.ReadFrom(r); err != nil {
 		return read, err
 	} else {
@@ -152,6 +172,15 @@ func (bh *Header) MarshalJSON() ([]byte, error) {
 		buffer.Write(bs)
 	}
 	buffer.WriteString(`,`)
+	if bh.Version() >= StateRootHashVersion {
+		buffer.WriteString(`"state_root_hash":`)
+		if bs, err := bh.StateRootHash.MarshalJSON(); err != nil {
+			return nil, err
+		} else {
+			buffer.Write(bs)
+		}
+		buffer.WriteString(`,`)
+	}
 	buffer.WriteString(`"formulator":`)
 	if bs, err := bh.Formulator.MarshalJSON(); err != nil {
 		return nil, err
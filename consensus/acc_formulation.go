@@ -50,17 +50,92 @@ func init() {
 	})
 }
 
+// formulationAccountVersion is the version of the serialization format of the formulation account
+// The version 1 appends the reward address and the heritor address
+const formulationAccountVersion = 1
+
 // FormulationAccount is a consensus.FormulationAccount
 // It is used to indentify Hyper formulator that supports the staking system
 type FormulationAccount struct {
 	account.Base
 	FormulationType FormulationType
 	KeyHash         common.PublicHash
+	RewardAddress   common.Address
+	HeritorAddress  common.Address
 	Amount          *amount.Amount
 	Policy          *HyperPolicy
 	StakingAmount   *amount.Amount
 }
 
+// EffectiveHyperPolicy returns the policy of the Hyper formulator that is applied at the height
+// The updated policy is pended until its effective height, so stakers can react before it is applied
+// The pended policy is resolved by the height when it is read, so the policy of the account can be the previous one
+func EffectiveHyperPolicy(loader data.Loader, acc *FormulationAccount, Height uint32) (*HyperPolicy, error) {
+	bs := loader.AccountData(acc.Address(), tagPendingHyperPolicy)
+	if len(bs) == 0 {
+		return acc.Policy, nil
+	}
+	EffectiveHeight, pc, err := readPendingHyperPolicy(bs)
+	if err != nil {
+		return nil, err
+	}
+	if Height < EffectiveHeight {
+		return acc.Policy, nil
+	}
+	return pc, nil
+}
+
+// initFormulatorAddresses sets the reward address and the heritor address of the new formulation account
+// If they are not given, rewards are credited to the formulator itself and the creator inherits it
+func initFormulatorAddresses(ctx *data.Context, acc *FormulationAccount, Creator common.Address, RewardAddress common.Address, HeritorAddress common.Address) error {
+	if RewardAddress.Equal(common.Address{}) {
+		acc.RewardAddress = acc.Address()
+	} else if is, err := ctx.IsExistAccount(RewardAddress); err != nil {
+		return err
+	} else if !is {
+		return ErrInvalidRewardAddress
+	} else {
+		acc.RewardAddress = RewardAddress
+	}
+	if HeritorAddress.Equal(common.Address{}) {
+		acc.HeritorAddress = Creator
+	} else if is, err := ctx.IsExistAccount(HeritorAddress); err != nil {
+		return err
+	} else if !is {
+		return ErrInvalidHeritorAddress
+	} else {
+		acc.HeritorAddress = HeritorAddress
+	}
+	return nil
+}
+
+func readPendingHyperPolicy(bs []byte) (uint32, *HyperPolicy, error) {
+	r := bytes.NewReader(bs)
+	EffectiveHeight, _, err := util.ReadUint32(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	pc := &HyperPolicy{
+		MinimumStaking: amount.NewCoinAmount(0, 0),
+		MaximumStaking: amount.NewCoinAmount(0, 0),
+	}
+	if _, err := pc.ReadFrom(r); err != nil {
+		return 0, nil, err
+	}
+	return EffectiveHeight, pc, nil
+}
+
+func writePendingHyperPolicy(EffectiveHeight uint32, pc *HyperPolicy) ([]byte, error) {
+	var buffer bytes.Buffer
+	if _, err := util.WriteUint32(&buffer, EffectiveHeight); err != nil {
+		return nil, err
+	}
+	if _, err := pc.WriteTo(&buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
 // Clone returns the clonend value of it
 func (acc *FormulationAccount) Clone() account.Account {
 	return &FormulationAccount{
@@ -71,6 +146,8 @@ func (acc *FormulationAccount) Clone() account.Account {
 		},
 		FormulationType: acc.FormulationType,
 		KeyHash:         acc.KeyHash.Clone(),
+		RewardAddress:   acc.RewardAddress.Clone(),
+		HeritorAddress:  acc.HeritorAddress.Clone(),
 		Policy:          acc.Policy.Clone(),
 		Amount:          acc.Amount.Clone(),
 		StakingAmount:   acc.StakingAmount.Clone(),
@@ -110,6 +187,25 @@ func (acc *FormulationAccount) WriteTo(w io.Writer) (int64, error) {
 	} else {
 		wrote += n
 	}
+	// the account that has no address is written as the version 0 to keep the serialization of accounts that are created before the version 1
+	if acc.RewardAddress.Equal(common.Address{}) && acc.HeritorAddress.Equal(common.Address{}) {
+		return wrote, nil
+	}
+	if n, err := util.WriteUint8(w, formulationAccountVersion); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := acc.RewardAddress.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := acc.HeritorAddress.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
 	return wrote, nil
 }
 
@@ -147,6 +243,30 @@ func (acc *FormulationAccount) ReadFrom(r io.Reader) (int64, error) {
 	} else {
 		read += n
 	}
+	// the account of the version 0 ends here and doesn't have the reward address and the heritor address
+	acc.RewardAddress = common.Address{}
+	acc.HeritorAddress = common.Address{}
+	if v, n, err := util.ReadUint8(r); err != nil {
+		if err != io.EOF {
+			return read, err
+		}
+		return read, nil
+	} else {
+		read += n
+		if v != formulationAccountVersion {
+			return read, ErrInvalidAccountVersion
+		}
+	}
+	if n, err := acc.RewardAddress.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if n, err := acc.HeritorAddress.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
 	return read, nil
 }
 
@@ -181,6 +301,20 @@ func (acc *FormulationAccount) MarshalJSON() ([]byte, error) {
 	} else {
 		buffer.Write(bs)
 	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"reward_address":`)
+	if bs, err := acc.RewardAddress.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"heritor_address":`)
+	if bs, err := acc.HeritorAddress.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
 	if acc.FormulationType == HyperFormulatorType {
 		buffer.WriteString(`,`)
 		buffer.WriteString(`"policy":`)
@@ -19,6 +19,8 @@ type Consensus struct {
 	height                   uint64
 	candidates               []*Rank
 	rankMap                  map[common.Address]*Rank
+	jailedMap                map[common.Address]*Rank
+	missedMap                map[common.Address]uint32
 	blocksFromSameFormulator uint32
 	ObserverKeyMap           map[common.PublicHash]bool
 	MaxBlocksPerFormulator   uint32
@@ -30,6 +32,8 @@ func NewConsensus(ObserverKeyMap map[common.PublicHash]bool, MaxBlocksPerFormula
 	cs := &Consensus{
 		candidates:             []*Rank{},
 		rankMap:                map[common.Address]*Rank{},
+		jailedMap:              map[common.Address]*Rank{},
+		missedMap:              map[common.Address]uint32{},
 		ObserverKeyMap:         ObserverKeyMap,
 		MaxBlocksPerFormulator: MaxBlocksPerFormulator,
 		FormulationAccountType: FormulationAccountType,
@@ -103,6 +107,23 @@ func (cs *Consensus) RanksInMap(FormulatorMap map[common.Address]bool, Limit int
 	return list, nil
 }
 
+// IsJailed returns the formulator is jailed out of candidates or not
+func (cs *Consensus) IsJailed(Formulator common.Address) bool {
+	cs.Lock()
+	defer cs.Unlock()
+
+	_, has := cs.jailedMap[Formulator]
+	return has
+}
+
+// MissedTurns returns a number of turns that the formulator missed in a row
+func (cs *Consensus) MissedTurns(Formulator common.Address) uint32 {
+	cs.Lock()
+	defer cs.Unlock()
+
+	return cs.missedMap[Formulator]
+}
+
 // IsFormulator returns the given information is correct or not
 func (cs *Consensus) IsFormulator(Formulator common.Address, Publichash common.PublicHash) bool {
 	cs.Lock()
@@ -150,17 +171,27 @@ func (cs *Consensus) ProcessContext(ctd *data.ContextData, HeaderHash hash.Hash2
 	cs.Lock()
 	defer cs.Unlock()
 
+	missed := cs.missedCandidates(bh.TimeoutCount)
+	// jailed heights are recorded to accounts by ApplyJailedFormulators when the block is executed
+	jailed := cs.jailTargets(&bh.ChainCoord, missed)
 	if bh.TimeoutCount > 0 {
 		if err := cs.forwardCandidates(int(bh.TimeoutCount)); err != nil {
 			return nil, err
 		}
 		cs.blocksFromSameFormulator = 0
 	}
+	for _, addr := range missed {
+		cs.missedMap[addr]++
+	}
+	delete(cs.missedMap, bh.Formulator)
 	cs.blocksFromSameFormulator++
 	if cs.blocksFromSameFormulator >= cs.MaxBlocksPerFormulator {
 		cs.forwardTop(HeaderHash)
 		cs.blocksFromSameFormulator = 0
 	}
+	for _, addr := range jailed {
+		cs.jailRank(addr)
+	}
 
 	phase := cs.largestPhase() + 1
 	for _, a := range ctd.CreatedAccountMap {
@@ -172,9 +203,34 @@ func (cs *Consensus) ProcessContext(ctd *data.ContextData, HeaderHash hash.Hash2
 			}
 		}
 	}
+	for _, a := range ctd.AccountMap {
+		if a.Type() == cs.FormulationAccountType {
+			acc := a.(*FormulationAccount)
+			if rank, has := cs.rankMap[acc.Address()]; has && !rank.PublicHash.Equal(acc.KeyHash) {
+				rank.PublicHash = acc.KeyHash.Clone()
+			}
+			if rank, has := cs.jailedMap[acc.Address()]; has && !rank.PublicHash.Equal(acc.KeyHash) {
+				rank.PublicHash = acc.KeyHash.Clone()
+			}
+		}
+	}
+	for _, e := range ctd.Events {
+		if se, is := e.(*SlashedEvent); is {
+			if err := cs.demoteRank(se.Formulator); err != nil {
+				return nil, err
+			}
+		}
+		if ue, is := e.(*UnjailedEvent); is {
+			if err := cs.unjailRank(ue.Formulator); err != nil {
+				return nil, err
+			}
+		}
+	}
 	for _, acc := range ctd.DeletedAccountMap {
 		if acc.Type() == cs.FormulationAccountType {
 			cs.removeRank(acc.Address())
+			delete(cs.jailedMap, acc.Address())
+			delete(cs.missedMap, acc.Address())
 		}
 	}
 
@@ -210,11 +266,50 @@ func (cs *Consensus) buildSaveData() ([]byte, error) {
 		if _, err := util.WriteUint8(&buffer, uint8(len(cs.ObserverKeyMap))); err != nil {
 			return nil, err
 		}
+		pubhashes := make([]common.PublicHash, 0, len(cs.ObserverKeyMap))
 		for k := range cs.ObserverKeyMap {
+			pubhashes = append(pubhashes, k)
+		}
+		sort.Slice(pubhashes, func(i, j int) bool {
+			return bytes.Compare(pubhashes[i][:], pubhashes[j][:]) < 0
+		})
+		for _, k := range pubhashes {
 			if _, err := k.WriteTo(&buffer); err != nil {
 				return nil, err
 			}
 		}
+		// maps are written by the order of addresses to make the same save data from the same state
+		if _, err := util.WriteUint32(&buffer, uint32(len(cs.jailedMap))); err != nil {
+			return nil, err
+		} else {
+			addrs := make([]common.Address, 0, len(cs.jailedMap))
+			for addr := range cs.jailedMap {
+				addrs = append(addrs, addr)
+			}
+			sortAddresses(addrs)
+			for _, addr := range addrs {
+				if _, err := cs.jailedMap[addr].WriteTo(&buffer); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if _, err := util.WriteUint32(&buffer, uint32(len(cs.missedMap))); err != nil {
+			return nil, err
+		} else {
+			addrs := make([]common.Address, 0, len(cs.missedMap))
+			for addr := range cs.missedMap {
+				addrs = append(addrs, addr)
+			}
+			sortAddresses(addrs)
+			for _, addr := range addrs {
+				if _, err := addr.WriteTo(&buffer); err != nil {
+					return nil, err
+				}
+				if _, err := util.WriteUint32(&buffer, cs.missedMap[addr]); err != nil {
+					return nil, err
+				}
+			}
+		}
 		SaveData = append(SaveData, buffer.Bytes()...)
 	}
 	return SaveData, nil
@@ -271,6 +366,35 @@ func (cs *Consensus) LoadFromSaveData(SaveData []byte) error {
 		}
 	}
 	cs.ObserverKeyMap = ObserverKeyMap
+	if Len, _, err := util.ReadUint32(r); err != nil {
+		return err
+	} else {
+		cs.jailedMap = map[common.Address]*Rank{}
+		for i := 0; i < int(Len); i++ {
+			s := new(Rank)
+			if _, err := s.ReadFrom(r); err != nil {
+				return err
+			} else {
+				cs.jailedMap[s.Address] = s
+			}
+		}
+	}
+	if Len, _, err := util.ReadUint32(r); err != nil {
+		return err
+	} else {
+		cs.missedMap = map[common.Address]uint32{}
+		for i := 0; i < int(Len); i++ {
+			var addr common.Address
+			if _, err := addr.ReadFrom(r); err != nil {
+				return err
+			}
+			if v, _, err := util.ReadUint32(r); err != nil {
+				return err
+			} else {
+				cs.missedMap[addr] = v
+			}
+		}
+	}
 	return nil
 }
 
@@ -304,7 +428,88 @@ func (cs *Consensus) removeRank(addr common.Address) {
 				candidates = append(candidates, s)
 			}
 		}
+		cs.candidates = candidates
+	}
+}
+
+// demoteRank moves the rank of the address behind all candidates
+func (cs *Consensus) demoteRank(addr common.Address) error {
+	rank, has := cs.rankMap[addr]
+	if !has {
+		return nil
 	}
+	phase := cs.largestPhase() + 1
+	cs.removeRank(addr)
+	rank.SetPhase(phase)
+	return cs.addRank(rank)
+}
+
+// ApplyJailedFormulators records the jailed height to formulators that are jailed by the block of the timeout count
+// It is called before transactions of the block are executed, so jailed heights are hashed with the context of the block
+func (cs *Consensus) ApplyJailedFormulators(ctx *data.Context, TimeoutCount uint32) error {
+	cs.Lock()
+	defer cs.Unlock()
+
+	for _, addr := range cs.jailTargets(ctx.ChainCoord(), cs.missedCandidates(TimeoutCount)) {
+		if exist, err := ctx.IsExistAccount(addr); err != nil {
+			return err
+		} else if exist {
+			ctx.SetAccountData(addr, tagJailed, toJailedValue(ctx.TargetHeight()))
+		}
+	}
+	return nil
+}
+
+// missedCandidates returns candidates that miss their turns by the timeout count
+func (cs *Consensus) missedCandidates(TimeoutCount uint32) []common.Address {
+	missed := []common.Address{}
+	for i := 0; i < int(TimeoutCount) && i < len(cs.candidates); i++ {
+		missed = append(missed, cs.candidates[i].Address)
+	}
+	return missed
+}
+
+// jailTargets returns missed candidates that reach the jail missed turns by missing the turn again
+// The last candidate is not jailed to keep the chain going
+func (cs *Consensus) jailTargets(chainCoord *common.Coordinate, missed []common.Address) []common.Address {
+	list := []common.Address{}
+	policy, has := gConsensusPolicyMap[chainCoord.ID()]
+	if !has || policy.JailMissedTurns == 0 {
+		return list
+	}
+	count := len(cs.candidates)
+	for _, addr := range missed {
+		if _, has := cs.rankMap[addr]; !has || count <= 1 {
+			continue
+		}
+		if cs.missedMap[addr]+1 >= policy.JailMissedTurns {
+			list = append(list, addr)
+			count--
+		}
+	}
+	return list
+}
+
+// jailRank moves the rank of the address out of candidates until it is unjailed
+func (cs *Consensus) jailRank(addr common.Address) {
+	rank, has := cs.rankMap[addr]
+	if !has || len(cs.candidates) <= 1 {
+		return
+	}
+	cs.removeRank(addr)
+	cs.jailedMap[addr] = rank
+	delete(cs.missedMap, addr)
+}
+
+// unjailRank returns the jailed rank of the address behind all candidates
+func (cs *Consensus) unjailRank(addr common.Address) error {
+	rank, has := cs.jailedMap[addr]
+	if !has {
+		return nil
+	}
+	delete(cs.jailedMap, addr)
+	rank.SetPhase(cs.largestPhase() + 1)
+	return cs.addRank(rank)
 }
 
 func (cs *Consensus) forwardCandidates(TimeoutCount int) error {
@@ -348,3 +553,9 @@ func InsertRankToList(ranks []*Rank, s *Rank) []*Rank {
 	ranks[idx] = s
 	return ranks
 }
+
+func sortAddresses(addrs []common.Address) {
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+}
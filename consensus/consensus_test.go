@@ -0,0 +1,424 @@
+package consensus
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/core/account"
+	"github.com/fletaio/core/amount"
+	"github.com/fletaio/core/block"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/core/transaction"
+	"github.com/fletaio/framework/chain"
+)
+
+const (
+	testChangeFormulatorKeyType transaction.Type = iota + 1
+	testRevokeType
+	testWithdrawType
+	testUpdateHyperPolicyType
+)
+
+func testPolicy() *ConsensusPolicy {
+	return &ConsensusPolicy{
+		RewardPerBlock:                  amount.NewCoinAmount(0, 0),
+		AlphaCreationAmount:             amount.NewCoinAmount(100, 0),
+		AlphaUnlockRequiredBlocks:       10,
+		HyperCreationAmount:             amount.NewCoinAmount(1000, 0),
+		HyperUnlockRequiredBlocks:       20,
+		HyperPolicyChangeRequiredBlocks: 5,
+		SlashRatio1000:                  100,
+		JailMissedTurns:                 2,
+		UnjailRequiredBlocks:            4,
+	}
+}
+
+// testContext returns the context of the target height 1 on the memory loader
+func testContext(t *testing.T, pc *ConsensusPolicy) (*data.Context, *data.Transactor) {
+	coord := common.NewCoordinate(0, 0)
+	if err := SetConsensusPolicy(coord, pc); err != nil {
+		t.Fatal(err)
+	}
+	act := data.NewAccounter(coord)
+	if err := act.RegisterType("consensus.FormulationAccount", 1); err != nil {
+		t.Fatal(err)
+	}
+	tran := data.NewTransactor(coord)
+	for name, tt := range map[string]transaction.Type{
+		"consensus.ChangeFormulatorKey": testChangeFormulatorKeyType,
+		"consensus.Revoke":              testRevokeType,
+		"consensus.Withdraw":            testWithdrawType,
+		"consensus.UpdateHyperPolicy":   testUpdateHyperPolicyType,
+	} {
+		if err := tran.RegisterType(name, tt, amount.NewCoinAmount(0, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	base := data.NewContext(data.NewEmptyLoader(coord, act, tran, data.NewEventer(coord)))
+	return base.NextContext(hash.Hash([]byte("1"))), tran
+}
+
+// testContextAt returns the next context of the context until the target height
+func testContextAt(ctx *data.Context, Height uint32) *data.Context {
+	for ctx.TargetHeight() < Height {
+		ctx = ctx.NextContext(ctx.Hash())
+	}
+	return ctx
+}
+
+func testKeyHash(i int) common.PublicHash {
+	var KeyHash common.PublicHash
+	KeyHash[0] = byte(i)
+	return KeyHash
+}
+
+func testFormulationAccount(t *testing.T, ctx *data.Context, n uint64, ft FormulationType) *FormulationAccount {
+	acc := &FormulationAccount{
+		Base: account.Base{
+			Type_:    1,
+			Address_: common.NewAddress(ctx.ChainCoord(), n),
+			Name_:    "formulator" + strconv.FormatUint(n, 10),
+			Balance_: amount.NewCoinAmount(10, 0),
+		},
+		FormulationType: ft,
+		KeyHash:         testKeyHash(int(n)),
+		Amount:          amount.NewCoinAmount(100, 0),
+		Policy: &HyperPolicy{
+			MinimumStaking: amount.NewCoinAmount(0, 0),
+			MaximumStaking: amount.NewCoinAmount(0, 0),
+		},
+		StakingAmount: amount.NewCoinAmount(0, 0),
+	}
+	if err := ctx.CreateAccount(acc); err != nil {
+		t.Fatal(err)
+	}
+	return acc
+}
+
+func testFormulationAccountOf(t *testing.T, ctx *data.Context, addr common.Address) *FormulationAccount {
+	acc, err := ctx.Account(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return acc.(*FormulationAccount)
+}
+
+func Test_EffectiveHyperPolicy(t *testing.T) {
+	ctx, tran := testContext(t, testPolicy())
+	frAcc := testFormulationAccount(t, ctx, 1, HyperFormulatorType)
+	ctx = testContextAt(ctx, 2)
+
+	tx := &UpdateHyperPolicy{
+		Base:  transaction.Base{Type_: testUpdateHyperPolicyType},
+		Seq_:  1,
+		From_: frAcc.Address(),
+		Policy: &HyperPolicy{
+			CommissionRatio1000: 50,
+			MinimumStaking:      amount.NewCoinAmount(0, 0),
+			MaximumStaking:      amount.NewCoinAmount(0, 0),
+		},
+	}
+	if _, err := tran.Execute(ctx, tx, common.NewCoordinate(ctx.TargetHeight(), 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the policy is pended until 2 + HyperPolicyChangeRequiredBlocks
+	for _, v := range []struct {
+		Height              uint32
+		CommissionRatio1000 uint32
+	}{
+		{2, 0},
+		{6, 0},
+		{7, 50},
+		{8, 50},
+	} {
+		pc, err := EffectiveHyperPolicy(ctx, testFormulationAccountOf(t, ctx, frAcc.Address()), v.Height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pc.CommissionRatio1000 != v.CommissionRatio1000 {
+			t.Fatal("invalid effective policy", v.Height, pc.CommissionRatio1000, v.CommissionRatio1000)
+		}
+	}
+
+	// the effective pended policy is applied to the account before the next one is pended
+	ctx = testContextAt(ctx, 9)
+	tx.Seq_ = 2
+	tx.Policy.CommissionRatio1000 = 80
+	if _, err := tran.Execute(ctx, tx, common.NewCoordinate(ctx.TargetHeight(), 0)); err != nil {
+		t.Fatal(err)
+	}
+	acc := testFormulationAccountOf(t, ctx, frAcc.Address())
+	if acc.Policy.CommissionRatio1000 != 50 {
+		t.Fatal("effective pended policy is not applied", acc.Policy.CommissionRatio1000)
+	}
+	for _, v := range []struct {
+		Height              uint32
+		CommissionRatio1000 uint32
+	}{
+		{13, 50},
+		{14, 80},
+	} {
+		pc, err := EffectiveHyperPolicy(ctx, acc, v.Height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pc.CommissionRatio1000 != v.CommissionRatio1000 {
+			t.Fatal("invalid effective policy", v.Height, pc.CommissionRatio1000, v.CommissionRatio1000)
+		}
+	}
+}
+
+func Test_KeyHashAt(t *testing.T) {
+	ctx, tran := testContext(t, testPolicy())
+	frAcc := testFormulationAccount(t, ctx, 1, AlphaFormulatorType)
+
+	// keys are changed by blocks of heights 3 and 7, so new keys sign from heights 4 and 8
+	for i, Height := range []uint32{3, 7} {
+		ctx = testContextAt(ctx, Height)
+		tx := &ChangeFormulatorKey{
+			Base:    transaction.Base{Type_: testChangeFormulatorKeyType},
+			Seq_:    uint64(i + 1),
+			From_:   frAcc.Address(),
+			KeyHash: testKeyHash(10 + i),
+		}
+		if _, err := tran.Execute(ctx, tx, common.NewCoordinate(ctx.TargetHeight(), 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx = testContextAt(ctx, 12)
+
+	acc := testFormulationAccountOf(t, ctx, frAcc.Address())
+	for _, v := range []struct {
+		Height  uint32
+		KeyHash common.PublicHash
+	}{
+		{1, testKeyHash(1)},
+		{3, testKeyHash(1)},
+		{4, testKeyHash(10)},
+		{7, testKeyHash(10)},
+		{8, testKeyHash(11)},
+		{11, testKeyHash(11)},
+	} {
+		KeyHash, err := keyHashAt(ctx, acc, v.Height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !KeyHash.Equal(v.KeyHash) {
+			t.Fatal("invalid key hash", v.Height, KeyHash[0], v.KeyHash[0])
+		}
+	}
+}
+
+func Test_RevokeWithdrawPreviousVersion(t *testing.T) {
+	ctx, tran := testContext(t, testPolicy())
+	frAcc := testFormulationAccount(t, ctx, 1, AlphaFormulatorType)
+	heritor := testFormulationAccount(t, ctx, 2, AlphaFormulatorType)
+
+	// the account of the version 0 is read without the heritor
+	var buffer bytes.Buffer
+	if _, err := frAcc.WriteTo(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	readAcc, err := ctx.Accounter().NewByType(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readAcc.ReadFrom(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if !readAcc.(*FormulationAccount).HeritorAddress.Equal(common.Address{}) {
+		t.Fatal("version 0 account has the heritor")
+	}
+
+	withdraw := &Withdraw{
+		Base:   transaction.Base{Type_: testWithdrawType},
+		Seq_:   1,
+		From_:  frAcc.Address(),
+		Amount: amount.NewCoinAmount(1, 0),
+	}
+	if err := tran.Validate(ctx, withdraw, []common.PublicHash{frAcc.KeyHash}); err != ErrInvalidHeritorAddress {
+		t.Fatal("withdraw without the heritor is validated", err)
+	}
+	if _, err := tran.Execute(ctx, withdraw, common.NewCoordinate(ctx.TargetHeight(), 0)); err != ErrInvalidHeritorAddress {
+		t.Fatal("withdraw without the heritor is executed", err)
+	}
+
+	// remaining coins of the account without the heritor are locked to the formulator itself as before
+	versioned := testFormulationAccount(t, ctx, 3, AlphaFormulatorType)
+	versioned.HeritorAddress = heritor.Address()
+	for _, v := range []struct {
+		name    string
+		acc     *FormulationAccount
+		Heritor common.Address
+		result  common.Address
+		err     error
+	}{
+		{"version 0 without the heritor", frAcc, common.Address{}, frAcc.Address(), nil},
+		{"version 0 with the heritor", frAcc, heritor.Address(), frAcc.Address(), nil},
+		{"version 1 without the heritor", versioned, common.Address{}, heritor.Address(), nil},
+		{"version 1 with the same heritor", versioned, heritor.Address(), heritor.Address(), nil},
+		{"version 1 with the other heritor", versioned, frAcc.Address(), common.Address{}, ErrInvalidHeritorAddress},
+	} {
+		revoke := &Revoke{
+			Base:    transaction.Base{Type_: testRevokeType},
+			Seq_:    1,
+			From_:   v.acc.Address(),
+			Heritor: v.Heritor,
+		}
+		addr, err := revokeHeritor(v.acc, revoke)
+		if err != v.err {
+			t.Fatal("invalid revoke heritor result", v.name, err, v.err)
+		}
+		if !addr.Equal(v.result) {
+			t.Fatal("invalid revoke heritor", v.name)
+		}
+	}
+
+	revoke := &Revoke{
+		Base:  transaction.Base{Type_: testRevokeType},
+		Seq_:  1,
+		From_: frAcc.Address(),
+	}
+	if _, err := tran.Execute(ctx, revoke, common.NewCoordinate(ctx.TargetHeight(), 0)); err != nil {
+		t.Fatal(err)
+	}
+	if is, err := ctx.IsExistAccount(frAcc.Address()); err != nil {
+		t.Fatal(err)
+	} else if is {
+		t.Fatal("revoked account exists")
+	}
+}
+
+func testEquivocation(ctx *data.Context, Formulator common.Address, Height uint32) *ReportEquivocation {
+	ha := &block.Header{
+		Base:       chain.Base{Version_: 1, Height_: Height},
+		ChainCoord: *ctx.ChainCoord(),
+		Formulator: Formulator,
+	}
+	hb := &block.Header{
+		Base:          chain.Base{Version_: 1, Height_: Height},
+		ChainCoord:    *ctx.ChainCoord(),
+		LevelRootHash: hash.Hash([]byte("other")),
+		Formulator:    Formulator,
+	}
+	return &ReportEquivocation{
+		HeaderA: ha,
+		HeaderB: hb,
+	}
+}
+
+func Test_ValidateEquivocation(t *testing.T) {
+	ctx, _ := testContext(t, testPolicy())
+	frAcc := testFormulationAccount(t, ctx, 1, AlphaFormulatorType)
+	ctx = testContextAt(ctx, 10)
+	ctx.SetAccountData(frAcc.Address(), toEquivocationKey(5, 0), []byte{1})
+
+	same := testEquivocation(ctx, frAcc.Address(), 8)
+	same.HeaderB = same.HeaderA
+
+	for _, v := range []struct {
+		name string
+		tx   *ReportEquivocation
+		err  error
+	}{
+		{"current height", testEquivocation(ctx, frAcc.Address(), 10), ErrInvalidEquivocation},
+		{"future height", testEquivocation(ctx, frAcc.Address(), 11), ErrInvalidEquivocation},
+		{"same hash", same, ErrInvalidEquivocation},
+		{"already slashed", testEquivocation(ctx, frAcc.Address(), 5), ErrAlreadySlashed},
+	} {
+		if _, err := validateEquivocation(ctx, v.tx); err != v.err {
+			t.Fatal("invalid equivocation result", v.name, err, v.err)
+		}
+	}
+}
+
+func Test_JailTargets(t *testing.T) {
+	coord := common.NewCoordinate(0, 0)
+	pc := testPolicy()
+	if err := SetConsensusPolicy(coord, pc); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []struct {
+		name      string
+		count     int
+		missed    map[int]uint32
+		turns     uint32
+		jailCount int
+	}{
+		{"reach the missed turns", 3, map[int]uint32{0: 1}, 2, 1},
+		{"not reach the missed turns", 3, map[int]uint32{0: 0}, 2, 0},
+		{"jailing is disabled", 3, map[int]uint32{0: 5}, 0, 0},
+		{"keep the last candidate", 2, map[int]uint32{0: 1, 1: 1}, 2, 1},
+		{"only candidate", 1, map[int]uint32{0: 1}, 2, 0},
+	} {
+		pc.JailMissedTurns = v.turns
+		cs := NewConsensus(nil, 10, 1)
+		for i := 0; i < v.count; i++ {
+			addr := common.NewAddress(coord, uint64(i+1))
+			if err := cs.addRank(NewRank(addr, testKeyHash(i+1), 0, hash.Hash(addr[:]))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		missed := cs.missedCandidates(uint32(len(v.missed)))
+		for i, addr := range missed {
+			cs.missedMap[addr] = v.missed[i]
+		}
+		jailed := cs.jailTargets(coord, missed)
+		if len(jailed) != v.jailCount {
+			t.Fatal("invalid jailed count", v.name, len(jailed), v.jailCount)
+		}
+		for _, addr := range jailed {
+			cs.jailRank(addr)
+		}
+		if cs.CandidateCount() != v.count-v.jailCount {
+			t.Fatal("invalid candidate count", v.name, cs.CandidateCount(), v.count-v.jailCount)
+		}
+	}
+}
+
+func Test_JailUnjailTiming(t *testing.T) {
+	pc := testPolicy()
+	ctx, _ := testContext(t, pc)
+	cs := NewConsensus(nil, 10, 1)
+	for i := 0; i < 3; i++ {
+		addr := testFormulationAccount(t, ctx, uint64(i+1), AlphaFormulatorType).Address()
+		if err := cs.addRank(NewRank(addr, testKeyHash(i+1), 0, hash.Hash(addr[:]))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx = testContextAt(ctx, 5)
+	top, err := cs.TopRank(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validateUnjail(ctx, top.Address); err != ErrNotJailedFormulator {
+		t.Fatal("not jailed formulator is unjailed", err)
+	}
+	cs.missedMap[top.Address] = pc.JailMissedTurns - 1
+	if err := cs.ApplyJailedFormulators(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if JailedHeight, is := fromJailedValue(ctx.AccountData(top.Address, tagJailed)); !is || JailedHeight != 5 {
+		t.Fatal("invalid jailed height", JailedHeight, is)
+	}
+
+	for _, v := range []struct {
+		Height uint32
+		err    error
+	}{
+		{6, ErrJailNotExpired},
+		{8, ErrJailNotExpired},
+		{9, nil},
+		{10, nil},
+	} {
+		ctx = testContextAt(ctx, v.Height)
+		if err := validateUnjail(ctx, top.Address); err != v.err {
+			t.Fatal("invalid unjail result", v.Height, err, v.err)
+		}
+	}
+}
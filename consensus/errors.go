@@ -30,4 +30,17 @@ var (
 	ErrNotExistConsensusPolicy        = errors.New("not exist formulator policy")
 	ErrFormulatorCreationLimited      = errors.New("formulator creation limited")
 	ErrUnauthorizedTransaction        = errors.New("unauthorized transaction")
+	ErrInvalidCommissionRatio         = errors.New("invalid commission ratio")
+	ErrInvalidStakingRange            = errors.New("invalid staking range")
+	ErrInvalidKeyHash                 = errors.New("invalid key hash")
+	ErrSameKeyHash                    = errors.New("same key hash")
+	ErrInvalidRewardAddress           = errors.New("invalid reward address")
+	ErrInvalidHeritorAddress          = errors.New("invalid heritor address")
+	ErrInvalidWithdrawAmount          = errors.New("invalid withdraw amount")
+	ErrInvalidEquivocation            = errors.New("invalid equivocation")
+	ErrAlreadySlashed                 = errors.New("already slashed")
+	ErrInvalidSlashRatio              = errors.New("invalid slash ratio")
+	ErrNotJailedFormulator            = errors.New("not jailed formulator")
+	ErrJailNotExpired                 = errors.New("jail not expired")
+	ErrInvalidAccountVersion          = errors.New("invalid account version")
 )
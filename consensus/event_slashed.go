@@ -0,0 +1,174 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/util"
+	"github.com/fletaio/core/amount"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/core/event"
+)
+
+func init() {
+	data.RegisterEvent("consensus.SlashedEvent", func(t event.Type) event.Event {
+		return &SlashedEvent{
+			Base: event.Base{
+				Coord_: &common.Coordinate{},
+				Type_:  t,
+			},
+			Amount:        amount.NewCoinAmount(0, 0),
+			StakingAmount: amount.NewCoinAmount(0, 0),
+		}
+	})
+}
+
+// SlashedEvent is a consensus.SlashedEvent
+// It is emitted when the formulator is slashed by the evidence of the equivocation
+type SlashedEvent struct {
+	event.Base
+	Formulator    common.Address
+	Height        uint32
+	TimeoutCount  uint32
+	Amount        *amount.Amount
+	StakingAmount *amount.Amount
+}
+
+// WriteTo is a serialization function
+func (e *SlashedEvent) WriteTo(w io.Writer) (int64, error) {
+	var wrote int64
+	if n, err := e.Base.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := e.Formulator.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteUint32(w, e.Height); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteUint32(w, e.TimeoutCount); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := e.Amount.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := e.StakingAmount.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	return wrote, nil
+}
+
+// ReadFrom is a deserialization function
+func (e *SlashedEvent) ReadFrom(r io.Reader) (int64, error) {
+	var read int64
+	if n, err := e.Base.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if n, err := e.Formulator.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if v, n, err := util.ReadUint32(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		e.Height = v
+	}
+	if v, n, err := util.ReadUint32(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		e.TimeoutCount = v
+	}
+	if n, err := e.Amount.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if n, err := e.StakingAmount.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	return read, nil
+}
+
+// MarshalJSON is a marshaler function
+func (e *SlashedEvent) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(`{`)
+	buffer.WriteString(`"coord":`)
+	if bs, err := e.Coord_.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"index":`)
+	if bs, err := json.Marshal(e.Index_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"type":`)
+	if bs, err := json.Marshal(e.Type_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"formulator":`)
+	if bs, err := e.Formulator.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"height":`)
+	if bs, err := json.Marshal(e.Height); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"timeout_count":`)
+	if bs, err := json.Marshal(e.TimeoutCount); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"amount":`)
+	if bs, err := e.Amount.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"staking_amount":`)
+	if bs, err := e.StakingAmount.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`}`)
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,96 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/core/event"
+)
+
+func init() {
+	data.RegisterEvent("consensus.UnjailedEvent", func(t event.Type) event.Event {
+		return &UnjailedEvent{
+			Base: event.Base{
+				Coord_: &common.Coordinate{},
+				Type_:  t,
+			},
+		}
+	})
+}
+
+// UnjailedEvent is a consensus.UnjailedEvent
+// It is emitted when the formulator requests to return to candidates from the jail
+type UnjailedEvent struct {
+	event.Base
+	Formulator common.Address
+}
+
+// WriteTo is a serialization function
+func (e *UnjailedEvent) WriteTo(w io.Writer) (int64, error) {
+	var wrote int64
+	if n, err := e.Base.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := e.Formulator.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	return wrote, nil
+}
+
+// ReadFrom is a deserialization function
+func (e *UnjailedEvent) ReadFrom(r io.Reader) (int64, error) {
+	var read int64
+	if n, err := e.Base.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if n, err := e.Formulator.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	return read, nil
+}
+
+// MarshalJSON is a marshaler function
+func (e *UnjailedEvent) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(`{`)
+	buffer.WriteString(`"coord":`)
+	if bs, err := e.Coord_.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"index":`)
+	if bs, err := json.Marshal(e.Index_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"type":`)
+	if bs, err := json.Marshal(e.Type_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"formulator":`)
+	if bs, err := e.Formulator.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`}`)
+	return buffer.Bytes(), nil
+}
@@ -12,8 +12,14 @@ import (
 
 var gConsensusPolicyMap = map[uint64]*ConsensusPolicy{}
 
-func SetConsensusPolicy(chainCoord *common.Coordinate, pc *ConsensusPolicy) {
+// SetConsensusPolicy validates the policy and sets it to the chain
+// Slash ratios are checked here because the misconfigured ratio would fail every report of the equivocation
+func SetConsensusPolicy(chainCoord *common.Coordinate, pc *ConsensusPolicy) error {
+	if pc.SlashRatio1000 > 1000 || pc.StakingSlashRatio1000 > 1000 {
+		return ErrInvalidSlashRatio
+	}
 	gConsensusPolicyMap[chainCoord.ID()] = pc
+	return nil
 }
 
 func GetConsensusPolicy(chainCoord *common.Coordinate) (*ConsensusPolicy, error) {
@@ -26,25 +32,30 @@ func GetConsensusPolicy(chainCoord *common.Coordinate) (*ConsensusPolicy, error)
 
 // ConsensusPolicy defines a staking policy user
 type ConsensusPolicy struct {
-	RewardPerBlock                *amount.Amount
-	PayRewardEveryBlocks          uint32
-	FormulatorCreationLimitHeight uint32
-	AlphaCreationAmount           *amount.Amount
-	AlphaEfficiency1000           uint32
-	AlphaUnlockRequiredBlocks     uint32
-	SigmaRequiredAlphaBlocks      uint32
-	SigmaRequiredAlphaCount       uint32
-	SigmaEfficiency1000           uint32
-	SigmaUnlockRequiredBlocks     uint32
-	OmegaRequiredSigmaBlocks      uint32
-	OmegaRequiredSigmaCount       uint32
-	OmegaEfficiency1000           uint32
-	OmegaUnlockRequiredBlocks     uint32
-	HyperCreationAmount           *amount.Amount
-	HyperEfficiency1000           uint32
-	HyperUnlockRequiredBlocks     uint32
-	StakingEfficiency1000         uint32
-	StakingUnlockRequiredBlocks   uint32
+	RewardPerBlock                  *amount.Amount
+	PayRewardEveryBlocks            uint32
+	FormulatorCreationLimitHeight   uint32
+	AlphaCreationAmount             *amount.Amount
+	AlphaEfficiency1000             uint32
+	AlphaUnlockRequiredBlocks       uint32
+	SigmaRequiredAlphaBlocks        uint32
+	SigmaRequiredAlphaCount         uint32
+	SigmaEfficiency1000             uint32
+	SigmaUnlockRequiredBlocks       uint32
+	OmegaRequiredSigmaBlocks        uint32
+	OmegaRequiredSigmaCount         uint32
+	OmegaEfficiency1000             uint32
+	OmegaUnlockRequiredBlocks       uint32
+	HyperCreationAmount             *amount.Amount
+	HyperEfficiency1000             uint32
+	HyperUnlockRequiredBlocks       uint32
+	StakingEfficiency1000           uint32
+	StakingUnlockRequiredBlocks     uint32
+	HyperPolicyChangeRequiredBlocks uint32
+	SlashRatio1000                  uint32
+	StakingSlashRatio1000           uint32
+	JailMissedTurns                 uint32
+	UnjailRequiredBlocks            uint32
 }
 
 // WriteTo is a serialization function
@@ -145,6 +156,31 @@ func (pc *ConsensusPolicy) WriteTo(w io.Writer) (int64, error) {
 	} else {
 		wrote += n
 	}
+	if n, err := util.WriteUint32(w, pc.HyperPolicyChangeRequiredBlocks); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteUint32(w, pc.SlashRatio1000); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteUint32(w, pc.StakingSlashRatio1000); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteUint32(w, pc.JailMissedTurns); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteUint32(w, pc.UnjailRequiredBlocks); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
 	return wrote, nil
 }
 
@@ -262,6 +298,36 @@ func (pc *ConsensusPolicy) ReadFrom(r io.Reader) (int64, error) {
 		read += n
 		pc.StakingUnlockRequiredBlocks = v
 	}
+	if v, n, err := util.ReadUint32(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		pc.HyperPolicyChangeRequiredBlocks = v
+	}
+	if v, n, err := util.ReadUint32(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		pc.SlashRatio1000 = v
+	}
+	if v, n, err := util.ReadUint32(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		pc.StakingSlashRatio1000 = v
+	}
+	if v, n, err := util.ReadUint32(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		pc.JailMissedTurns = v
+	}
+	if v, n, err := util.ReadUint32(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		pc.UnjailRequiredBlocks = v
+	}
 	return read, nil
 }
 
@@ -401,6 +467,41 @@ func (pc *ConsensusPolicy) MarshalJSON() ([]byte, error) {
 	} else {
 		buffer.Write(bs)
 	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"hyper_policy_change_required_blocks":`)
+	if bs, err := json.Marshal(pc.HyperPolicyChangeRequiredBlocks); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"slash_ratio_1000":`)
+	if bs, err := json.Marshal(pc.SlashRatio1000); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"staking_slash_ratio_1000":`)
+	if bs, err := json.Marshal(pc.StakingSlashRatio1000); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"jail_missed_turns":`)
+	if bs, err := json.Marshal(pc.JailMissedTurns); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"unjail_required_blocks":`)
+	if bs, err := json.Marshal(pc.UnjailRequiredBlocks); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
 	buffer.WriteString(`}`)
 	return buffer.Bytes(), nil
 }
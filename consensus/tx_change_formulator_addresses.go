@@ -0,0 +1,238 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/common/util"
+	"github.com/fletaio/core/amount"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/core/transaction"
+)
+
+func init() {
+	data.RegisterTransaction("consensus.ChangeFormulatorAddresses", func(t transaction.Type) transaction.Transaction {
+		return &ChangeFormulatorAddresses{
+			Base: transaction.Base{
+				Type_: t,
+			},
+		}
+	}, func(loader data.Loader, t transaction.Transaction, signers []common.PublicHash) error {
+		tx := t.(*ChangeFormulatorAddresses)
+		if tx.Seq() <= loader.Seq(tx.From()) {
+			return ErrInvalidSequence
+		}
+		if tx.RewardAddress.Equal(common.Address{}) {
+			return ErrInvalidRewardAddress
+		}
+		if tx.HeritorAddress.Equal(common.Address{}) || tx.HeritorAddress.Equal(tx.From()) {
+			return ErrInvalidHeritorAddress
+		}
+
+		acc, err := loader.Account(tx.From())
+		if err != nil {
+			return err
+		}
+		frAcc, is := acc.(*FormulationAccount)
+		if !is {
+			return ErrInvalidAccountType
+		}
+		if err := loader.Accounter().Validate(loader, frAcc, signers); err != nil {
+			return err
+		}
+		return nil
+	}, func(ctx *data.Context, Fee *amount.Amount, t transaction.Transaction, coord *common.Coordinate) (ret interface{}, rerr error) {
+		tx := t.(*ChangeFormulatorAddresses)
+		if tx.RewardAddress.Equal(common.Address{}) {
+			return nil, ErrInvalidRewardAddress
+		}
+		if tx.HeritorAddress.Equal(common.Address{}) || tx.HeritorAddress.Equal(tx.From()) {
+			return nil, ErrInvalidHeritorAddress
+		}
+
+		sn := ctx.Snapshot()
+		defer ctx.Revert(sn)
+
+		if tx.Seq() != ctx.Seq(tx.From())+1 {
+			return nil, ErrInvalidSequence
+		}
+		ctx.AddSeq(tx.From())
+
+		acc, err := ctx.Account(tx.From())
+		if err != nil {
+			return nil, err
+		}
+		frAcc, is := acc.(*FormulationAccount)
+		if !is {
+			return nil, ErrInvalidAccountType
+		}
+		if err := frAcc.SubBalance(Fee); err != nil {
+			return nil, err
+		}
+		if is, err := ctx.IsExistAccount(tx.RewardAddress); err != nil {
+			return nil, err
+		} else if !is {
+			return nil, ErrInvalidRewardAddress
+		}
+		if is, err := ctx.IsExistAccount(tx.HeritorAddress); err != nil {
+			return nil, err
+		} else if !is {
+			return nil, ErrInvalidHeritorAddress
+		}
+		frAcc.RewardAddress = tx.RewardAddress
+		frAcc.HeritorAddress = tx.HeritorAddress
+
+		ctx.Commit(sn)
+		return nil, nil
+	})
+}
+
+// ChangeFormulatorAddresses is a consensus.ChangeFormulatorAddresses
+// It is used to change the address that receives rewards and the address that inherits the formulator when it is revoked
+type ChangeFormulatorAddresses struct {
+	transaction.Base
+	Seq_           uint64
+	From_          common.Address
+	RewardAddress  common.Address
+	HeritorAddress common.Address
+}
+
+// IsUTXO returns false
+func (tx *ChangeFormulatorAddresses) IsUTXO() bool {
+	return false
+}
+
+// From returns the creator of the transaction
+func (tx *ChangeFormulatorAddresses) From() common.Address {
+	return tx.From_
+}
+
+// Seq returns the sequence of the transaction
+func (tx *ChangeFormulatorAddresses) Seq() uint64 {
+	return tx.Seq_
+}
+
+// Hash returns the hash value of it
+func (tx *ChangeFormulatorAddresses) Hash() hash.Hash256 {
+	return hash.DoubleHashByWriterTo(tx)
+}
+
+// Recipients returns the new reward and heritor addresses
+func (tx *ChangeFormulatorAddresses) Recipients() []common.Address {
+	return []common.Address{tx.RewardAddress, tx.HeritorAddress}
+}
+
+// WriteTo is a serialization function
+func (tx *ChangeFormulatorAddresses) WriteTo(w io.Writer) (int64, error) {
+	var wrote int64
+	if n, err := tx.Base.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteUint64(w, tx.Seq_); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.From_.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.RewardAddress.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.HeritorAddress.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	return wrote, nil
+}
+
+// ReadFrom is a deserialization function
+func (tx *ChangeFormulatorAddresses) ReadFrom(r io.Reader) (int64, error) {
+	var read int64
+	if n, err := tx.Base.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if v, n, err := util.ReadUint64(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		tx.Seq_ = v
+	}
+	if n, err := tx.From_.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if n, err := tx.RewardAddress.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if n, err := tx.HeritorAddress.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	return read, nil
+}
+
+// MarshalJSON is a marshaler function
+func (tx *ChangeFormulatorAddresses) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(`{`)
+	buffer.WriteString(`"type":`)
+	if bs, err := json.Marshal(tx.Type_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"timestamp":`)
+	if bs, err := json.Marshal(tx.Timestamp_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"seq":`)
+	if bs, err := json.Marshal(tx.Seq_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"from":`)
+	if bs, err := tx.From_.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"reward_address":`)
+	if bs, err := tx.RewardAddress.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"heritor_address":`)
+	if bs, err := tx.HeritorAddress.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`}`)
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,211 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/common/util"
+	"github.com/fletaio/core/amount"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/core/transaction"
+)
+
+func init() {
+	data.RegisterTransaction("consensus.ChangeFormulatorKey", func(t transaction.Type) transaction.Transaction {
+		return &ChangeFormulatorKey{
+			Base: transaction.Base{
+				Type_: t,
+			},
+		}
+	}, func(loader data.Loader, t transaction.Transaction, signers []common.PublicHash) error {
+		tx := t.(*ChangeFormulatorKey)
+		if tx.Seq() <= loader.Seq(tx.From()) {
+			return ErrInvalidSequence
+		}
+		if tx.KeyHash.Equal(common.PublicHash{}) {
+			return ErrInvalidKeyHash
+		}
+
+		acc, err := loader.Account(tx.From())
+		if err != nil {
+			return err
+		}
+		frAcc, is := acc.(*FormulationAccount)
+		if !is {
+			return ErrInvalidAccountType
+		}
+		if frAcc.KeyHash.Equal(tx.KeyHash) {
+			return ErrSameKeyHash
+		}
+		// it should be signed by the current key, so the new key takes effect after the block is connected
+		if err := loader.Accounter().Validate(loader, frAcc, signers); err != nil {
+			return err
+		}
+		return nil
+	}, func(ctx *data.Context, Fee *amount.Amount, t transaction.Transaction, coord *common.Coordinate) (ret interface{}, rerr error) {
+		tx := t.(*ChangeFormulatorKey)
+		if tx.KeyHash.Equal(common.PublicHash{}) {
+			return nil, ErrInvalidKeyHash
+		}
+
+		sn := ctx.Snapshot()
+		defer ctx.Revert(sn)
+
+		if tx.Seq() != ctx.Seq(tx.From())+1 {
+			return nil, ErrInvalidSequence
+		}
+		ctx.AddSeq(tx.From())
+
+		acc, err := ctx.Account(tx.From())
+		if err != nil {
+			return nil, err
+		}
+		frAcc, is := acc.(*FormulationAccount)
+		if !is {
+			return nil, ErrInvalidAccountType
+		}
+		if frAcc.KeyHash.Equal(tx.KeyHash) {
+			return nil, ErrSameKeyHash
+		}
+		if err := frAcc.SubBalance(Fee); err != nil {
+			return nil, err
+		}
+		// the previous key is kept with the height that the new key takes effect, so evidences of past blocks can be validated
+		// the block of the current height is signed by the key before changes of the block
+		if key := toKeyHistoryKey(ctx.TargetHeight() + 1); len(ctx.AccountData(tx.From(), key)) == 0 {
+			PrevKeyHash := frAcc.KeyHash.Clone()
+			ctx.SetAccountData(tx.From(), key, PrevKeyHash[:])
+		}
+		frAcc.KeyHash = tx.KeyHash.Clone()
+
+		ctx.Commit(sn)
+		return nil, nil
+	})
+}
+
+// ChangeFormulatorKey is a consensus.ChangeFormulatorKey
+// It is used to replace the key of the formulation account without losing the age of the formulator
+type ChangeFormulatorKey struct {
+	transaction.Base
+	Seq_    uint64
+	From_   common.Address
+	KeyHash common.PublicHash
+}
+
+// IsUTXO returns false
+func (tx *ChangeFormulatorKey) IsUTXO() bool {
+	return false
+}
+
+// From returns the creator of the transaction
+func (tx *ChangeFormulatorKey) From() common.Address {
+	return tx.From_
+}
+
+// Seq returns the sequence of the transaction
+func (tx *ChangeFormulatorKey) Seq() uint64 {
+	return tx.Seq_
+}
+
+// Hash returns the hash value of it
+func (tx *ChangeFormulatorKey) Hash() hash.Hash256 {
+	return hash.DoubleHashByWriterTo(tx)
+}
+
+// WriteTo is a serialization function
+func (tx *ChangeFormulatorKey) WriteTo(w io.Writer) (int64, error) {
+	var wrote int64
+	if n, err := tx.Base.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteUint64(w, tx.Seq_); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.From_.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.KeyHash.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	return wrote, nil
+}
+
+// ReadFrom is a deserialization function
+func (tx *ChangeFormulatorKey) ReadFrom(r io.Reader) (int64, error) {
+	var read int64
+	if n, err := tx.Base.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if v, n, err := util.ReadUint64(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		tx.Seq_ = v
+	}
+	if n, err := tx.From_.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if n, err := tx.KeyHash.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	return read, nil
+}
+
+// MarshalJSON is a marshaler function
+func (tx *ChangeFormulatorKey) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(`{`)
+	buffer.WriteString(`"type":`)
+	if bs, err := json.Marshal(tx.Type_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"timestamp":`)
+	if bs, err := json.Marshal(tx.Timestamp_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"seq":`)
+	if bs, err := json.Marshal(tx.Seq_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"from":`)
+	if bs, err := tx.From_.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"key_hash":`)
+	if bs, err := tx.KeyHash.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`}`)
+	return buffer.Bytes(), nil
+}
@@ -21,82 +21,92 @@ func init() {
 			},
 		}
 	}, func(loader data.Loader, t transaction.Transaction, signers []common.PublicHash) error {
-		tx := t.(*CreateAlpha)
-		if len(tx.Name) < 8 || len(tx.Name) > 16 {
-			return ErrInvalidAccountName
-		}
+		return validateCreateAlpha(loader, t.(*CreateAlpha), signers)
+	}, func(ctx *data.Context, Fee *amount.Amount, t transaction.Transaction, coord *common.Coordinate) (ret interface{}, rerr error) {
+		// the formulation account is created with default addresses by the transaction of the previous version
+		return executeCreateAlpha(ctx, Fee, t.(*CreateAlpha), common.Address{}, common.Address{}, coord)
+	})
+}
 
-		if tx.Seq() <= loader.Seq(tx.From()) {
-			return ErrInvalidSequence
-		}
+func validateCreateAlpha(loader data.Loader, tx *CreateAlpha, signers []common.PublicHash) error {
+	if len(tx.Name) < 8 || len(tx.Name) > 16 {
+		return ErrInvalidAccountName
+	}
 
-		fromAcc, err := loader.Account(tx.From())
-		if err != nil {
-			return err
-		}
+	if tx.Seq() <= loader.Seq(tx.From()) {
+		return ErrInvalidSequence
+	}
 
-		if err := loader.Accounter().Validate(loader, fromAcc, signers); err != nil {
-			return err
-		}
-		return nil
-	}, func(ctx *data.Context, Fee *amount.Amount, t transaction.Transaction, coord *common.Coordinate) (ret interface{}, rerr error) {
-		tx := t.(*CreateAlpha)
-		if len(tx.Name) < 8 || len(tx.Name) > 16 {
-			return nil, ErrInvalidAccountName
-		}
+	fromAcc, err := loader.Account(tx.From())
+	if err != nil {
+		return err
+	}
 
-		policy, has := gConsensusPolicyMap[ctx.ChainCoord().ID()]
-		if !has {
-			return nil, ErrNotExistConsensusPolicy
-		}
-		if ctx.TargetHeight() < policy.FormulatorCreationLimitHeight {
-			return nil, ErrFormulatorCreationLimited
-		}
+	if err := loader.Accounter().Validate(loader, fromAcc, signers); err != nil {
+		return err
+	}
+	return nil
+}
 
-		sn := ctx.Snapshot()
-		defer ctx.Revert(sn)
+func executeCreateAlpha(ctx *data.Context, Fee *amount.Amount, tx *CreateAlpha, RewardAddress common.Address, HeritorAddress common.Address, coord *common.Coordinate) (interface{}, error) {
+	if len(tx.Name) < 8 || len(tx.Name) > 16 {
+		return nil, ErrInvalidAccountName
+	}
 
-		if tx.Seq() != ctx.Seq(tx.From())+1 {
-			return nil, ErrInvalidSequence
-		}
-		ctx.AddSeq(tx.From())
+	policy, has := gConsensusPolicyMap[ctx.ChainCoord().ID()]
+	if !has {
+		return nil, ErrNotExistConsensusPolicy
+	}
+	if ctx.TargetHeight() < policy.FormulatorCreationLimitHeight {
+		return nil, ErrFormulatorCreationLimited
+	}
 
-		fromAcc, err := ctx.Account(tx.From())
+	sn := ctx.Snapshot()
+	defer ctx.Revert(sn)
+
+	if tx.Seq() != ctx.Seq(tx.From())+1 {
+		return nil, ErrInvalidSequence
+	}
+	ctx.AddSeq(tx.From())
+
+	fromAcc, err := ctx.Account(tx.From())
+	if err != nil {
+		return nil, err
+	}
+	if err := fromAcc.SubBalance(Fee); err != nil {
+		return nil, err
+	}
+	if err := fromAcc.SubBalance(policy.AlphaCreationAmount); err != nil {
+		return nil, err
+	}
+
+	addr := common.NewAddress(coord, 0)
+	if is, err := ctx.IsExistAccount(addr); err != nil {
+		return nil, err
+	} else if is {
+		return nil, ErrExistAddress
+	} else if isn, err := ctx.IsExistAccountName(tx.Name); err != nil {
+		return nil, err
+	} else if isn {
+		return nil, ErrExistAccountName
+	} else {
+		a, err := ctx.Accounter().NewByTypeName("consensus.FormulationAccount")
 		if err != nil {
 			return nil, err
 		}
-		if err := fromAcc.SubBalance(Fee); err != nil {
-			return nil, err
-		}
-		if err := fromAcc.SubBalance(policy.AlphaCreationAmount); err != nil {
-			return nil, err
-		}
-
-		addr := common.NewAddress(coord, 0)
-		if is, err := ctx.IsExistAccount(addr); err != nil {
-			return nil, err
-		} else if is {
-			return nil, ErrExistAddress
-		} else if isn, err := ctx.IsExistAccountName(tx.Name); err != nil {
+		acc := a.(*FormulationAccount)
+		acc.Address_ = addr
+		acc.Name_ = tx.Name
+		acc.FormulationType = AlphaFormulatorType
+		acc.KeyHash = tx.KeyHash
+		if err := initFormulatorAddresses(ctx, acc, tx.From(), RewardAddress, HeritorAddress); err != nil {
 			return nil, err
-		} else if isn {
-			return nil, ErrExistAccountName
-		} else {
-			a, err := ctx.Accounter().NewByTypeName("consensus.FormulationAccount")
-			if err != nil {
-				return nil, err
-			}
-			acc := a.(*FormulationAccount)
-			acc.Address_ = addr
-			acc.Name_ = tx.Name
-			acc.FormulationType = AlphaFormulatorType
-			acc.KeyHash = tx.KeyHash
-			acc.Amount = policy.AlphaCreationAmount
-			ctx.CreateAccount(acc)
 		}
-		ctx.Commit(sn)
-		return nil, nil
-	})
+		acc.Amount = policy.AlphaCreationAmount
+		ctx.CreateAccount(acc)
+	}
+	ctx.Commit(sn)
+	return nil, nil
 }
 
 // CreateAlpha is a consensus.CreateAlpha
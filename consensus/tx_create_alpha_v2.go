@@ -0,0 +1,162 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/core/amount"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/core/transaction"
+)
+
+func init() {
+	data.RegisterTransaction("consensus.CreateAlphaV2", func(t transaction.Type) transaction.Transaction {
+		return &CreateAlphaV2{
+			CreateAlpha: CreateAlpha{
+				Base: transaction.Base{
+					Type_: t,
+				},
+			},
+		}
+	}, func(loader data.Loader, t transaction.Transaction, signers []common.PublicHash) error {
+		tx := t.(*CreateAlphaV2)
+		return validateCreateAlpha(loader, &tx.CreateAlpha, signers)
+	}, func(ctx *data.Context, Fee *amount.Amount, t transaction.Transaction, coord *common.Coordinate) (ret interface{}, rerr error) {
+		tx := t.(*CreateAlphaV2)
+		return executeCreateAlpha(ctx, Fee, &tx.CreateAlpha, tx.RewardAddress, tx.HeritorAddress, coord)
+	})
+}
+
+// CreateAlphaV2 is a consensus.CreateAlphaV2
+// It is used to make formulation account with the reward address and the heritor address
+// Empty addresses are replaced by the formulation account itself and the creator of the transaction
+type CreateAlphaV2 struct {
+	CreateAlpha
+	RewardAddress  common.Address
+	HeritorAddress common.Address
+}
+
+// Hash returns the hash value of it
+func (tx *CreateAlphaV2) Hash() hash.Hash256 {
+	return hash.DoubleHashByWriterTo(tx)
+}
+
+// Recipients returns the reward address and the heritor address of the transaction
+func (tx *CreateAlphaV2) Recipients() []common.Address {
+	addrs := []common.Address{}
+	if !tx.RewardAddress.Equal(common.Address{}) {
+		addrs = append(addrs, tx.RewardAddress)
+	}
+	if !tx.HeritorAddress.Equal(common.Address{}) {
+		addrs = append(addrs, tx.HeritorAddress)
+	}
+	return addrs
+}
+
+// WriteTo is a serialization function
+func (tx *CreateAlphaV2) WriteTo(w io.Writer) (int64, error) {
+	var wrote int64
+	if n, err := tx.CreateAlpha.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.RewardAddress.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.HeritorAddress.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	return wrote, nil
+}
+
+// ReadFrom is a deserialization function
+func (tx *CreateAlphaV2) ReadFrom(r io.Reader) (int64, error) {
+	var read int64
+	if n, err := tx.CreateAlpha.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if n, err := tx.RewardAddress.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if n, err := tx.HeritorAddress.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	return read, nil
+}
+
+// MarshalJSON is a marshaler function
+func (tx *CreateAlphaV2) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(`{`)
+	buffer.WriteString(`"type":`)
+	if bs, err := json.Marshal(tx.Type_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"timestamp":`)
+	if bs, err := json.Marshal(tx.Timestamp_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"seq":`)
+	if bs, err := json.Marshal(tx.Seq_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"from":`)
+	if bs, err := tx.From_.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"name":`)
+	if bs, err := json.Marshal(tx.Name); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"key_hash":`)
+	if bs, err := tx.KeyHash.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"reward_address":`)
+	if bs, err := tx.RewardAddress.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"heritor_address":`)
+	if bs, err := tx.HeritorAddress.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`}`)
+	return buffer.Bytes(), nil
+}
@@ -21,87 +21,97 @@ func init() {
 			},
 		}
 	}, func(loader data.Loader, t transaction.Transaction, signers []common.PublicHash) error {
-		tx := t.(*CreateHyper)
-		if len(tx.Name) < 8 || len(tx.Name) > 16 {
-			return ErrInvalidAccountName
-		}
+		return validateCreateHyper(loader, t.(*CreateHyper), signers)
+	}, func(ctx *data.Context, Fee *amount.Amount, t transaction.Transaction, coord *common.Coordinate) (ret interface{}, rerr error) {
+		// the formulation account is created with default addresses by the transaction of the previous version
+		return executeCreateHyper(ctx, Fee, t.(*CreateHyper), common.Address{}, common.Address{}, coord)
+	})
+}
 
-		initAddr := common.NewAddress(common.NewCoordinate(0, 0), 0)
-		if tx.From().Equal(initAddr) {
-			return ErrUnauthorizedTransaction
-		}
+func validateCreateHyper(loader data.Loader, tx *CreateHyper, signers []common.PublicHash) error {
+	if len(tx.Name) < 8 || len(tx.Name) > 16 {
+		return ErrInvalidAccountName
+	}
 
-		if tx.Seq() <= loader.Seq(tx.From()) {
-			return ErrInvalidSequence
-		}
+	initAddr := common.NewAddress(common.NewCoordinate(0, 0), 0)
+	if tx.From().Equal(initAddr) {
+		return ErrUnauthorizedTransaction
+	}
 
-		fromAcc, err := loader.Account(tx.From())
-		if err != nil {
-			return err
-		}
+	if tx.Seq() <= loader.Seq(tx.From()) {
+		return ErrInvalidSequence
+	}
 
-		if err := loader.Accounter().Validate(loader, fromAcc, signers); err != nil {
-			return err
-		}
-		return nil
-	}, func(ctx *data.Context, Fee *amount.Amount, t transaction.Transaction, coord *common.Coordinate) (ret interface{}, rerr error) {
-		tx := t.(*CreateHyper)
-		if len(tx.Name) < 8 || len(tx.Name) > 16 {
-			return nil, ErrInvalidAccountName
-		}
+	fromAcc, err := loader.Account(tx.From())
+	if err != nil {
+		return err
+	}
 
-		policy, has := gConsensusPolicyMap[ctx.ChainCoord().ID()]
-		if !has {
-			return nil, ErrNotExistConsensusPolicy
-		}
-		if ctx.TargetHeight() < policy.FormulatorCreationLimitHeight {
-			return nil, ErrFormulatorCreationLimited
-		}
+	if err := loader.Accounter().Validate(loader, fromAcc, signers); err != nil {
+		return err
+	}
+	return nil
+}
 
-		sn := ctx.Snapshot()
-		defer ctx.Revert(sn)
+func executeCreateHyper(ctx *data.Context, Fee *amount.Amount, tx *CreateHyper, RewardAddress common.Address, HeritorAddress common.Address, coord *common.Coordinate) (interface{}, error) {
+	if len(tx.Name) < 8 || len(tx.Name) > 16 {
+		return nil, ErrInvalidAccountName
+	}
 
-		if tx.Seq() != ctx.Seq(tx.From())+1 {
-			return nil, ErrInvalidSequence
-		}
-		ctx.AddSeq(tx.From())
+	policy, has := gConsensusPolicyMap[ctx.ChainCoord().ID()]
+	if !has {
+		return nil, ErrNotExistConsensusPolicy
+	}
+	if ctx.TargetHeight() < policy.FormulatorCreationLimitHeight {
+		return nil, ErrFormulatorCreationLimited
+	}
+
+	sn := ctx.Snapshot()
+	defer ctx.Revert(sn)
+
+	if tx.Seq() != ctx.Seq(tx.From())+1 {
+		return nil, ErrInvalidSequence
+	}
+	ctx.AddSeq(tx.From())
+
+	fromAcc, err := ctx.Account(tx.From())
+	if err != nil {
+		return nil, err
+	}
+	if err := fromAcc.SubBalance(Fee); err != nil {
+		return nil, err
+	}
+	if err := fromAcc.SubBalance(policy.HyperCreationAmount); err != nil {
+		return nil, err
+	}
 
-		fromAcc, err := ctx.Account(tx.From())
+	addr := common.NewAddress(coord, 0)
+	if is, err := ctx.IsExistAccount(addr); err != nil {
+		return nil, err
+	} else if is {
+		return nil, ErrExistAddress
+	} else if isn, err := ctx.IsExistAccountName(tx.Name); err != nil {
+		return nil, err
+	} else if isn {
+		return nil, ErrExistAccountName
+	} else {
+		a, err := ctx.Accounter().NewByTypeName("consensus.FormulationAccount")
 		if err != nil {
 			return nil, err
 		}
-		if err := fromAcc.SubBalance(Fee); err != nil {
+		acc := a.(*FormulationAccount)
+		acc.Address_ = addr
+		acc.Name_ = tx.Name
+		acc.FormulationType = HyperFormulatorType
+		acc.KeyHash = tx.KeyHash
+		if err := initFormulatorAddresses(ctx, acc, tx.From(), RewardAddress, HeritorAddress); err != nil {
 			return nil, err
 		}
-		if err := fromAcc.SubBalance(policy.HyperCreationAmount); err != nil {
-			return nil, err
-		}
-
-		addr := common.NewAddress(coord, 0)
-		if is, err := ctx.IsExistAccount(addr); err != nil {
-			return nil, err
-		} else if is {
-			return nil, ErrExistAddress
-		} else if isn, err := ctx.IsExistAccountName(tx.Name); err != nil {
-			return nil, err
-		} else if isn {
-			return nil, ErrExistAccountName
-		} else {
-			a, err := ctx.Accounter().NewByTypeName("consensus.FormulationAccount")
-			if err != nil {
-				return nil, err
-			}
-			acc := a.(*FormulationAccount)
-			acc.Address_ = addr
-			acc.Name_ = tx.Name
-			acc.FormulationType = HyperFormulatorType
-			acc.KeyHash = tx.KeyHash
-			acc.Amount = policy.HyperCreationAmount
-			ctx.CreateAccount(acc)
-		}
-		ctx.Commit(sn)
-		return nil, nil
-	})
+		acc.Amount = policy.HyperCreationAmount
+		ctx.CreateAccount(acc)
+	}
+	ctx.Commit(sn)
+	return nil, nil
 }
 
 // CreateHyper is a consensus.CreateHyper
@@ -0,0 +1,378 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/common/util"
+	"github.com/fletaio/core/amount"
+	"github.com/fletaio/core/block"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/core/transaction"
+)
+
+func init() {
+	data.RegisterTransaction("consensus.ReportEquivocation", func(t transaction.Type) transaction.Transaction {
+		return &ReportEquivocation{
+			Base: transaction.Base{
+				Type_: t,
+			},
+			HeaderA: &block.Header{},
+			HeaderB: &block.Header{},
+		}
+	}, func(loader data.Loader, t transaction.Transaction, signers []common.PublicHash) error {
+		tx := t.(*ReportEquivocation)
+		if tx.Seq() <= loader.Seq(tx.From()) {
+			return ErrInvalidSequence
+		}
+		if _, err := validateEquivocation(loader, tx); err != nil {
+			return err
+		}
+
+		fromAcc, err := loader.Account(tx.From())
+		if err != nil {
+			return err
+		}
+		if err := loader.Accounter().Validate(loader, fromAcc, signers); err != nil {
+			return err
+		}
+		return nil
+	}, func(ctx *data.Context, Fee *amount.Amount, t transaction.Transaction, coord *common.Coordinate) (ret interface{}, rerr error) {
+		tx := t.(*ReportEquivocation)
+
+		policy, has := gConsensusPolicyMap[ctx.ChainCoord().ID()]
+		if !has {
+			return nil, ErrNotExistConsensusPolicy
+		}
+
+		sn := ctx.Snapshot()
+		defer ctx.Revert(sn)
+
+		if tx.Seq() != ctx.Seq(tx.From())+1 {
+			return nil, ErrInvalidSequence
+		}
+		ctx.AddSeq(tx.From())
+
+		fromAcc, err := ctx.Account(tx.From())
+		if err != nil {
+			return nil, err
+		}
+		if err := fromAcc.SubBalance(Fee); err != nil {
+			return nil, err
+		}
+
+		frAcc, err := validateEquivocation(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		SlashedAmount := frAcc.Amount.MulC(int64(policy.SlashRatio1000)).DivC(1000)
+		frAcc.Amount = frAcc.Amount.Sub(SlashedAmount)
+
+		SlashedStakingAmount := amount.NewCoinAmount(0, 0)
+		if frAcc.FormulationType == HyperFormulatorType && policy.StakingSlashRatio1000 > 0 {
+			keys, err := ctx.AccountDataKeys(frAcc.Address(), TagStaking)
+			if err != nil {
+				return nil, err
+			}
+			for _, k := range keys {
+				if _, is := FromStakingKey(k); is {
+					bs := ctx.AccountData(frAcc.Address(), k)
+					if len(bs) == 0 {
+						return nil, ErrInvalidStakingAddress
+					}
+					StakingAmount := amount.NewAmountFromBytes(bs)
+					SlashAmount := StakingAmount.MulC(int64(policy.StakingSlashRatio1000)).DivC(1000)
+					if frAcc.StakingAmount.Less(SlashAmount) {
+						return nil, ErrCriticalStakingAmount
+					}
+					frAcc.StakingAmount = frAcc.StakingAmount.Sub(SlashAmount)
+					ctx.SetAccountData(frAcc.Address(), k, StakingAmount.Sub(SlashAmount).Bytes())
+					SlashedStakingAmount = SlashedStakingAmount.Add(SlashAmount)
+				}
+			}
+		}
+		ctx.SetAccountData(frAcc.Address(), toEquivocationKey(tx.HeaderA.Height(), tx.HeaderA.TimeoutCount), []byte{1})
+
+		ev, err := ctx.Eventer().NewByTypeName("consensus.SlashedEvent")
+		if err != nil {
+			return nil, err
+		}
+		e := ev.(*SlashedEvent)
+		e.Coord_ = coord.Clone()
+		e.Formulator = frAcc.Address()
+		e.Height = tx.HeaderA.Height()
+		e.TimeoutCount = tx.HeaderA.TimeoutCount
+		e.Amount = SlashedAmount
+		e.StakingAmount = SlashedStakingAmount
+		if err := ctx.EmitEvent(e); err != nil {
+			return nil, err
+		}
+
+		ctx.Commit(sn)
+		return nil, nil
+	})
+}
+
+// validateEquivocation returns the formulation account that signs both headers of the report
+func validateEquivocation(loader data.Loader, tx *ReportEquivocation) (*FormulationAccount, error) {
+	ha, hb := tx.HeaderA, tx.HeaderB
+	if ha.Height() != hb.Height() || ha.TimeoutCount != hb.TimeoutCount {
+		return nil, ErrInvalidEquivocation
+	}
+	if !ha.Formulator.Equal(hb.Formulator) {
+		return nil, ErrInvalidEquivocation
+	}
+	if !ha.ChainCoord.Equal(loader.ChainCoord()) || !hb.ChainCoord.Equal(loader.ChainCoord()) {
+		return nil, ErrInvalidEquivocation
+	}
+	// the evidence of the height that is not connected yet cannot be validated by the key of the height
+	if ha.Height() >= loader.TargetHeight() {
+		return nil, ErrInvalidEquivocation
+	}
+	HashA := ha.Hash()
+	HashB := hb.Hash()
+	if HashA.Equal(HashB) {
+		return nil, ErrInvalidEquivocation
+	}
+
+	acc, err := loader.Account(ha.Formulator)
+	if err != nil {
+		return nil, err
+	}
+	frAcc, is := acc.(*FormulationAccount)
+	if !is {
+		return nil, ErrInvalidAccountType
+	}
+	if bs := loader.AccountData(frAcc.Address(), toEquivocationKey(ha.Height(), ha.TimeoutCount)); len(bs) > 0 {
+		return nil, ErrAlreadySlashed
+	}
+	KeyHash, err := keyHashAt(loader, frAcc, ha.Height())
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range []struct {
+		h   hash.Hash256
+		sig common.Signature
+	}{{HashA, tx.SignatureA}, {HashB, tx.SignatureB}} {
+		pubkey, err := common.RecoverPubkey(v.h, v.sig)
+		if err != nil {
+			return nil, err
+		}
+		if !KeyHash.Equal(common.NewPublicHash(pubkey)) {
+			return nil, ErrInvalidAccountSigner
+		}
+	}
+	return frAcc, nil
+}
+
+// keyHashAt returns the key hash of the formulation account that signs the header of the height
+// Previous keys are kept with heights that changed keys take effect, so the earliest one after the height is used
+func keyHashAt(loader data.Loader, frAcc *FormulationAccount, Height uint32) (common.PublicHash, error) {
+	keys, err := loader.AccountDataKeys(frAcc.Address(), tagKeyHistory)
+	if err != nil {
+		return common.PublicHash{}, err
+	}
+	KeyHash := frAcc.KeyHash
+	var EffectiveHeight uint32
+	for _, k := range keys {
+		h, is := fromKeyHistoryKey(k)
+		if !is || h <= Height {
+			continue
+		}
+		if EffectiveHeight == 0 || h < EffectiveHeight {
+			bs := loader.AccountData(frAcc.Address(), k)
+			if len(bs) != len(KeyHash) {
+				return common.PublicHash{}, ErrInvalidKeyHash
+			}
+			copy(KeyHash[:], bs)
+			EffectiveHeight = h
+		}
+	}
+	return KeyHash, nil
+}
+
+// ReportEquivocation is a consensus.ReportEquivocation
+// It is used to report the formulator that signs two different headers of the same height and the same timeout count
+type ReportEquivocation struct {
+	transaction.Base
+	Seq_       uint64
+	From_      common.Address
+	HeaderA    *block.Header
+	SignatureA common.Signature
+	HeaderB    *block.Header
+	SignatureB common.Signature
+}
+
+// IsUTXO returns false
+func (tx *ReportEquivocation) IsUTXO() bool {
+	return false
+}
+
+// From returns the creator of the transaction
+func (tx *ReportEquivocation) From() common.Address {
+	return tx.From_
+}
+
+// Seq returns the sequence of the transaction
+func (tx *ReportEquivocation) Seq() uint64 {
+	return tx.Seq_
+}
+
+// Hash returns the hash value of it
+func (tx *ReportEquivocation) Hash() hash.Hash256 {
+	return hash.DoubleHashByWriterTo(tx)
+}
+
+// Recipients returns the formulator that is slashed by the evidence
+func (tx *ReportEquivocation) Recipients() []common.Address {
+	return []common.Address{tx.HeaderA.Formulator}
+}
+
+// WriteTo is a serialization function
+func (tx *ReportEquivocation) WriteTo(w io.Writer) (int64, error) {
+	var wrote int64
+	if n, err := tx.Base.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteUint64(w, tx.Seq_); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.From_.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.HeaderA.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.SignatureA.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.HeaderB.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.SignatureB.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	return wrote, nil
+}
+
+// ReadFrom is a deserialization function
+func (tx *ReportEquivocation) ReadFrom(r io.Reader) (int64, error) {
+	var read int64
+	if n, err := tx.Base.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if v, n, err := util.ReadUint64(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		tx.Seq_ = v
+	}
+	if n, err := tx.From_.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if n, err := tx.HeaderA.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if n, err := tx.SignatureA.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if n, err := tx.HeaderB.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if n, err := tx.SignatureB.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	return read, nil
+}
+
+// MarshalJSON is a marshaler function
+func (tx *ReportEquivocation) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(`{`)
+	buffer.WriteString(`"type":`)
+	if bs, err := json.Marshal(tx.Type_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"timestamp":`)
+	if bs, err := json.Marshal(tx.Timestamp_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"seq":`)
+	if bs, err := json.Marshal(tx.Seq_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"from":`)
+	if bs, err := tx.From_.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"header_a":`)
+	if bs, err := tx.HeaderA.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"signature_a":`)
+	if bs, err := tx.SignatureA.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"header_b":`)
+	if bs, err := tx.HeaderB.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"signature_b":`)
+	if bs, err := tx.SignatureB.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`}`)
+	return buffer.Bytes(), nil
+}
@@ -34,6 +34,9 @@ func init() {
 		if !is {
 			return ErrInvalidAccountType
 		}
+		if _, err := revokeHeritor(frAcc, tx); err != nil {
+			return err
+		}
 		if err := loader.Accounter().Validate(loader, frAcc, signers); err != nil {
 			return err
 		}
@@ -53,11 +56,6 @@ func init() {
 			return nil, ErrNotExistConsensusPolicy
 		}
 
-		heritorAcc, err := ctx.Account(tx.From())
-		if err != nil {
-			return nil, err
-		}
-
 		acc, err := ctx.Account(tx.From())
 		if err != nil {
 			return nil, err
@@ -66,6 +64,16 @@ func init() {
 		if !is {
 			return nil, ErrInvalidAccountType
 		}
+
+		// the formulation account is deleted, so remaining coins are locked to the heritor
+		heritor, err := revokeHeritor(frAcc, tx)
+		if err != nil {
+			return nil, err
+		}
+		heritorAcc, err := ctx.Account(heritor)
+		if err != nil {
+			return nil, err
+		}
 		switch frAcc.FormulationType {
 		case AlphaFormulatorType:
 			if err := frAcc.SubBalance(Fee); err != nil {
@@ -120,6 +128,22 @@ func init() {
 	})
 }
 
+// revokeHeritor returns the address that inherits the formulation account that is revoked
+// The heritor of the transaction should be empty or same with the heritor of the account
+// The account of the previous version doesn't have the heritor, so remaining coins are locked to the formulator itself as before
+func revokeHeritor(frAcc *FormulationAccount, tx *Revoke) (common.Address, error) {
+	if frAcc.HeritorAddress.Equal(common.Address{}) {
+		return tx.From(), nil
+	}
+	if frAcc.HeritorAddress.Equal(frAcc.Address()) {
+		return common.Address{}, ErrInvalidHeritorAddress
+	}
+	if !tx.Heritor.Equal(common.Address{}) && !tx.Heritor.Equal(frAcc.HeritorAddress) {
+		return common.Address{}, ErrInvalidHeritorAddress
+	}
+	return frAcc.HeritorAddress, nil
+}
+
 // Revoke is a consensus.Revoke
 // It is used to remove formulation account and get back staked coin
 type Revoke struct {
@@ -149,6 +173,14 @@ func (tx *Revoke) Hash() hash.Hash256 {
 	return hash.DoubleHashByWriterTo(tx)
 }
 
+// Recipients returns the heritor of the transaction
+func (tx *Revoke) Recipients() []common.Address {
+	if tx.Heritor.Equal(common.Address{}) {
+		return nil
+	}
+	return []common.Address{tx.Heritor}
+}
+
 // WriteTo is a serialization function
 func (tx *Revoke) WriteTo(w io.Writer) (int64, error) {
 	var wrote int64
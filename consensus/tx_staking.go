@@ -42,10 +42,14 @@ func init() {
 		if frAcc.FormulationType != HyperFormulatorType {
 			return ErrInvalidAccountType
 		}
-		if !frAcc.Policy.MinimumStaking.IsZero() && tx.Amount.Less(frAcc.Policy.MinimumStaking) {
+		pc, err := EffectiveHyperPolicy(loader, frAcc, loader.TargetHeight())
+		if err != nil {
+			return err
+		}
+		if !pc.MinimumStaking.IsZero() && tx.Amount.Less(pc.MinimumStaking) {
 			return ErrInvalidStakingAmount
 		}
-		if !frAcc.Policy.MaximumStaking.IsZero() && frAcc.Policy.MaximumStaking.Less(tx.Amount) {
+		if !pc.MaximumStaking.IsZero() && pc.MaximumStaking.Less(tx.Amount) {
 			return ErrInvalidStakingAmount
 		}
 
@@ -83,10 +87,14 @@ func init() {
 		if frAcc.FormulationType != HyperFormulatorType {
 			return nil, ErrInvalidAccountType
 		}
-		if !frAcc.Policy.MinimumStaking.IsZero() && tx.Amount.Less(frAcc.Policy.MinimumStaking) {
+		pc, err := EffectiveHyperPolicy(ctx, frAcc, ctx.TargetHeight())
+		if err != nil {
+			return nil, err
+		}
+		if !pc.MinimumStaking.IsZero() && tx.Amount.Less(pc.MinimumStaking) {
 			return nil, ErrInsufficientStakingAmount
 		}
-		if !frAcc.Policy.MaximumStaking.IsZero() && frAcc.Policy.MaximumStaking.Less(tx.Amount) {
+		if !pc.MaximumStaking.IsZero() && pc.MaximumStaking.Less(tx.Amount) {
 			return nil, ErrExceedStakingAmount
 		}
 
@@ -146,6 +154,11 @@ func (tx *Staking) Hash() hash.Hash256 {
 	return hash.DoubleHashByWriterTo(tx)
 }
 
+// Recipients returns the hyper formulator that receives the staking
+func (tx *Staking) Recipients() []common.Address {
+	return []common.Address{tx.HyperFormulator}
+}
+
 // WriteTo is a serialization function
 func (tx *Staking) WriteTo(w io.Writer) (int64, error) {
 	var wrote int64
@@ -0,0 +1,207 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/common/util"
+	"github.com/fletaio/core/amount"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/core/transaction"
+)
+
+func init() {
+	data.RegisterTransaction("consensus.Unjail", func(t transaction.Type) transaction.Transaction {
+		return &Unjail{
+			Base: transaction.Base{
+				Type_: t,
+			},
+		}
+	}, func(loader data.Loader, t transaction.Transaction, signers []common.PublicHash) error {
+		tx := t.(*Unjail)
+		if tx.Seq() <= loader.Seq(tx.From()) {
+			return ErrInvalidSequence
+		}
+
+		acc, err := loader.Account(tx.From())
+		if err != nil {
+			return err
+		}
+		frAcc, is := acc.(*FormulationAccount)
+		if !is {
+			return ErrInvalidAccountType
+		}
+		if err := loader.Accounter().Validate(loader, frAcc, signers); err != nil {
+			return err
+		}
+		if err := validateUnjail(loader, frAcc.Address()); err != nil {
+			return err
+		}
+		return nil
+	}, func(ctx *data.Context, Fee *amount.Amount, t transaction.Transaction, coord *common.Coordinate) (ret interface{}, rerr error) {
+		tx := t.(*Unjail)
+		sn := ctx.Snapshot()
+		defer ctx.Revert(sn)
+
+		if tx.Seq() != ctx.Seq(tx.From())+1 {
+			return nil, ErrInvalidSequence
+		}
+		ctx.AddSeq(tx.From())
+
+		acc, err := ctx.Account(tx.From())
+		if err != nil {
+			return nil, err
+		}
+		frAcc, is := acc.(*FormulationAccount)
+		if !is {
+			return nil, ErrInvalidAccountType
+		}
+		if err := validateUnjail(ctx, frAcc.Address()); err != nil {
+			return nil, err
+		}
+		if err := frAcc.SubBalance(Fee); err != nil {
+			return nil, err
+		}
+		ctx.SetAccountData(frAcc.Address(), tagJailed, nil)
+
+		// the rank is kept by the consensus, so the formulator returns to candidates when the block is connected
+		ev, err := ctx.Eventer().NewByTypeName("consensus.UnjailedEvent")
+		if err != nil {
+			return nil, err
+		}
+		e := ev.(*UnjailedEvent)
+		e.Coord_ = coord.Clone()
+		e.Formulator = frAcc.Address()
+		if err := ctx.EmitEvent(e); err != nil {
+			return nil, err
+		}
+
+		ctx.Commit(sn)
+		return nil, nil
+	})
+}
+
+// validateUnjail returns an error if the formulator is not jailed or the jail duration is not passed
+func validateUnjail(loader data.Loader, addr common.Address) error {
+	policy, has := gConsensusPolicyMap[loader.ChainCoord().ID()]
+	if !has {
+		return ErrNotExistConsensusPolicy
+	}
+	JailedHeight, is := fromJailedValue(loader.AccountData(addr, tagJailed))
+	if !is {
+		return ErrNotJailedFormulator
+	}
+	if loader.TargetHeight() < JailedHeight+policy.UnjailRequiredBlocks {
+		return ErrJailNotExpired
+	}
+	return nil
+}
+
+// Unjail is a consensus.Unjail
+// It is used to return the jailed formulator to candidates
+type Unjail struct {
+	transaction.Base
+	Seq_  uint64
+	From_ common.Address
+}
+
+// IsUTXO returns false
+func (tx *Unjail) IsUTXO() bool {
+	return false
+}
+
+// From returns the creator of the transaction
+func (tx *Unjail) From() common.Address {
+	return tx.From_
+}
+
+// Seq returns the sequence of the transaction
+func (tx *Unjail) Seq() uint64 {
+	return tx.Seq_
+}
+
+// Hash returns the hash value of it
+func (tx *Unjail) Hash() hash.Hash256 {
+	return hash.DoubleHashByWriterTo(tx)
+}
+
+// WriteTo is a serialization function
+func (tx *Unjail) WriteTo(w io.Writer) (int64, error) {
+	var wrote int64
+	if n, err := tx.Base.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteUint64(w, tx.Seq_); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.From_.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	return wrote, nil
+}
+
+// ReadFrom is a deserialization function
+func (tx *Unjail) ReadFrom(r io.Reader) (int64, error) {
+	var read int64
+	if n, err := tx.Base.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if v, n, err := util.ReadUint64(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		tx.Seq_ = v
+	}
+	if n, err := tx.From_.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	return read, nil
+}
+
+// MarshalJSON is a marshaler function
+func (tx *Unjail) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(`{`)
+	buffer.WriteString(`"type":`)
+	if bs, err := json.Marshal(tx.Type_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"timestamp":`)
+	if bs, err := json.Marshal(tx.Timestamp_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"seq":`)
+	if bs, err := json.Marshal(tx.Seq_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"from":`)
+	if bs, err := tx.From_.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`}`)
+	return buffer.Bytes(), nil
+}
@@ -148,6 +148,11 @@ func (tx *Unstaking) Hash() hash.Hash256 {
 	return hash.DoubleHashByWriterTo(tx)
 }
 
+// Recipients returns the hyper formulator that returns the staking
+func (tx *Unstaking) Recipients() []common.Address {
+	return []common.Address{tx.HyperFormulator}
+}
+
 // WriteTo is a serialization function
 func (tx *Unstaking) WriteTo(w io.Writer) (int64, error) {
 	var wrote int64
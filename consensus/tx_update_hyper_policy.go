@@ -0,0 +1,243 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/common/util"
+	"github.com/fletaio/core/amount"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/core/transaction"
+)
+
+func init() {
+	data.RegisterTransaction("consensus.UpdateHyperPolicy", func(t transaction.Type) transaction.Transaction {
+		return &UpdateHyperPolicy{
+			Base: transaction.Base{
+				Type_: t,
+			},
+			Policy: &HyperPolicy{
+				MinimumStaking: amount.NewCoinAmount(0, 0),
+				MaximumStaking: amount.NewCoinAmount(0, 0),
+			},
+		}
+	}, func(loader data.Loader, t transaction.Transaction, signers []common.PublicHash) error {
+		tx := t.(*UpdateHyperPolicy)
+		if tx.Seq() <= loader.Seq(tx.From()) {
+			return ErrInvalidSequence
+		}
+		if err := validateHyperPolicy(tx.Policy); err != nil {
+			return err
+		}
+
+		acc, err := loader.Account(tx.From())
+		if err != nil {
+			return err
+		}
+		frAcc, is := acc.(*FormulationAccount)
+		if !is {
+			return ErrInvalidAccountType
+		}
+		if frAcc.FormulationType != HyperFormulatorType {
+			return ErrInvalidAccountType
+		}
+		if err := loader.Accounter().Validate(loader, frAcc, signers); err != nil {
+			return err
+		}
+		return nil
+	}, func(ctx *data.Context, Fee *amount.Amount, t transaction.Transaction, coord *common.Coordinate) (ret interface{}, rerr error) {
+		tx := t.(*UpdateHyperPolicy)
+		if err := validateHyperPolicy(tx.Policy); err != nil {
+			return nil, err
+		}
+
+		policy, has := gConsensusPolicyMap[ctx.ChainCoord().ID()]
+		if !has {
+			return nil, ErrNotExistConsensusPolicy
+		}
+
+		sn := ctx.Snapshot()
+		defer ctx.Revert(sn)
+
+		if tx.Seq() != ctx.Seq(tx.From())+1 {
+			return nil, ErrInvalidSequence
+		}
+		ctx.AddSeq(tx.From())
+
+		acc, err := ctx.Account(tx.From())
+		if err != nil {
+			return nil, err
+		}
+		frAcc, is := acc.(*FormulationAccount)
+		if !is {
+			return nil, ErrInvalidAccountType
+		}
+		if frAcc.FormulationType != HyperFormulatorType {
+			return nil, ErrInvalidAccountType
+		}
+		if err := frAcc.SubBalance(Fee); err != nil {
+			return nil, err
+		}
+
+		// the pended policy that is already effective is applied before pending the new one
+		if pc, err := EffectiveHyperPolicy(ctx, frAcc, ctx.TargetHeight()); err != nil {
+			return nil, err
+		} else {
+			frAcc.Policy = pc.Clone()
+		}
+		if policy.HyperPolicyChangeRequiredBlocks == 0 {
+			frAcc.Policy = tx.Policy.Clone()
+			ctx.SetAccountData(tx.From(), tagPendingHyperPolicy, nil)
+		} else {
+			bs, err := writePendingHyperPolicy(ctx.TargetHeight()+policy.HyperPolicyChangeRequiredBlocks, tx.Policy)
+			if err != nil {
+				return nil, err
+			}
+			ctx.SetAccountData(tx.From(), tagPendingHyperPolicy, bs)
+		}
+
+		ctx.Commit(sn)
+		return nil, nil
+	})
+}
+
+func validateHyperPolicy(pc *HyperPolicy) error {
+	if pc.CommissionRatio1000 > 1000 {
+		return ErrInvalidCommissionRatio
+	}
+	zero := amount.NewCoinAmount(0, 0)
+	if pc.MinimumStaking.Less(zero) || pc.MaximumStaking.Less(zero) {
+		return ErrInvalidStakingRange
+	}
+	if !pc.MaximumStaking.IsZero() && pc.MaximumStaking.Less(pc.MinimumStaking) {
+		return ErrInvalidStakingRange
+	}
+	return nil
+}
+
+// UpdateHyperPolicy is a consensus.UpdateHyperPolicy
+// It is used to change the policy of Hyper formulator after HyperPolicyChangeRequiredBlocks of the consensus policy
+type UpdateHyperPolicy struct {
+	transaction.Base
+	Seq_   uint64
+	From_  common.Address
+	Policy *HyperPolicy
+}
+
+// IsUTXO returns false
+func (tx *UpdateHyperPolicy) IsUTXO() bool {
+	return false
+}
+
+// From returns the creator of the transaction
+func (tx *UpdateHyperPolicy) From() common.Address {
+	return tx.From_
+}
+
+// Seq returns the sequence of the transaction
+func (tx *UpdateHyperPolicy) Seq() uint64 {
+	return tx.Seq_
+}
+
+// Hash returns the hash value of it
+func (tx *UpdateHyperPolicy) Hash() hash.Hash256 {
+	return hash.DoubleHashByWriterTo(tx)
+}
+
+// WriteTo is a serialization function
+func (tx *UpdateHyperPolicy) WriteTo(w io.Writer) (int64, error) {
+	var wrote int64
+	if n, err := tx.Base.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteUint64(w, tx.Seq_); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.From_.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.Policy.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	return wrote, nil
+}
+
+// ReadFrom is a deserialization function
+func (tx *UpdateHyperPolicy) ReadFrom(r io.Reader) (int64, error) {
+	var read int64
+	if n, err := tx.Base.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if v, n, err := util.ReadUint64(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		tx.Seq_ = v
+	}
+	if n, err := tx.From_.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if n, err := tx.Policy.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	return read, nil
+}
+
+// MarshalJSON is a marshaler function
+func (tx *UpdateHyperPolicy) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(`{`)
+	buffer.WriteString(`"type":`)
+	if bs, err := json.Marshal(tx.Type_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"timestamp":`)
+	if bs, err := json.Marshal(tx.Timestamp_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"seq":`)
+	if bs, err := json.Marshal(tx.Seq_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"from":`)
+	if bs, err := tx.From_.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"policy":`)
+	if bs, err := tx.Policy.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`}`)
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,213 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/common/util"
+	"github.com/fletaio/core/amount"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/core/transaction"
+)
+
+func init() {
+	data.RegisterTransaction("consensus.Withdraw", func(t transaction.Type) transaction.Transaction {
+		return &Withdraw{
+			Base: transaction.Base{
+				Type_: t,
+			},
+			Amount: amount.NewCoinAmount(0, 0),
+		}
+	}, func(loader data.Loader, t transaction.Transaction, signers []common.PublicHash) error {
+		tx := t.(*Withdraw)
+		if tx.Seq() <= loader.Seq(tx.From()) {
+			return ErrInvalidSequence
+		}
+		if tx.Amount.IsZero() || tx.Amount.Less(amount.NewCoinAmount(0, 0)) {
+			return ErrInvalidWithdrawAmount
+		}
+
+		acc, err := loader.Account(tx.From())
+		if err != nil {
+			return err
+		}
+		frAcc, is := acc.(*FormulationAccount)
+		if !is {
+			return ErrInvalidAccountType
+		}
+		// the account of the previous version doesn't have the heritor and it should be set by ChangeFormulatorAddresses before
+		if frAcc.HeritorAddress.Equal(common.Address{}) || frAcc.HeritorAddress.Equal(frAcc.Address()) {
+			return ErrInvalidHeritorAddress
+		}
+		if err := loader.Accounter().Validate(loader, frAcc, signers); err != nil {
+			return err
+		}
+		return nil
+	}, func(ctx *data.Context, Fee *amount.Amount, t transaction.Transaction, coord *common.Coordinate) (ret interface{}, rerr error) {
+		tx := t.(*Withdraw)
+		if tx.Amount.IsZero() || tx.Amount.Less(amount.NewCoinAmount(0, 0)) {
+			return nil, ErrInvalidWithdrawAmount
+		}
+
+		sn := ctx.Snapshot()
+		defer ctx.Revert(sn)
+
+		if tx.Seq() != ctx.Seq(tx.From())+1 {
+			return nil, ErrInvalidSequence
+		}
+		ctx.AddSeq(tx.From())
+
+		acc, err := ctx.Account(tx.From())
+		if err != nil {
+			return nil, err
+		}
+		frAcc, is := acc.(*FormulationAccount)
+		if !is {
+			return nil, ErrInvalidAccountType
+		}
+		if frAcc.HeritorAddress.Equal(common.Address{}) || frAcc.HeritorAddress.Equal(frAcc.Address()) {
+			return nil, ErrInvalidHeritorAddress
+		}
+		heritorAcc, err := ctx.Account(frAcc.HeritorAddress)
+		if err != nil {
+			return nil, err
+		}
+		if err := frAcc.SubBalance(Fee); err != nil {
+			return nil, err
+		}
+		if err := frAcc.SubBalance(tx.Amount); err != nil {
+			return nil, err
+		}
+		heritorAcc.AddBalance(tx.Amount)
+
+		ctx.Commit(sn)
+		return nil, nil
+	})
+}
+
+// Withdraw is a consensus.Withdraw
+// It is used to move the balance of the formulation account to the heritor address without revoking it
+type Withdraw struct {
+	transaction.Base
+	Seq_   uint64
+	From_  common.Address
+	Amount *amount.Amount
+}
+
+// IsUTXO returns false
+func (tx *Withdraw) IsUTXO() bool {
+	return false
+}
+
+// From returns the creator of the transaction
+func (tx *Withdraw) From() common.Address {
+	return tx.From_
+}
+
+// Seq returns the sequence of the transaction
+func (tx *Withdraw) Seq() uint64 {
+	return tx.Seq_
+}
+
+// Hash returns the hash value of it
+func (tx *Withdraw) Hash() hash.Hash256 {
+	return hash.DoubleHashByWriterTo(tx)
+}
+
+// WriteTo is a serialization function
+func (tx *Withdraw) WriteTo(w io.Writer) (int64, error) {
+	var wrote int64
+	if n, err := tx.Base.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteUint64(w, tx.Seq_); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.From_.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.Amount.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	return wrote, nil
+}
+
+// ReadFrom is a deserialization function
+func (tx *Withdraw) ReadFrom(r io.Reader) (int64, error) {
+	var read int64
+	if n, err := tx.Base.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if v, n, err := util.ReadUint64(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		tx.Seq_ = v
+	}
+	if n, err := tx.From_.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	if n, err := tx.Amount.ReadFrom(r); err != nil {
+		return read, err
+	} else {
+		read += n
+	}
+	return read, nil
+}
+
+// MarshalJSON is a marshaler function
+func (tx *Withdraw) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(`{`)
+	buffer.WriteString(`"type":`)
+	if bs, err := json.Marshal(tx.Type_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"timestamp":`)
+	if bs, err := json.Marshal(tx.Timestamp_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"seq":`)
+	if bs, err := json.Marshal(tx.Seq_); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"from":`)
+	if bs, err := tx.From_.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"amount":`)
+	if bs, err := tx.Amount.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`}`)
+	return buffer.Bytes(), nil
+}
@@ -2,14 +2,19 @@ package consensus
 
 import (
 	"bytes"
+	"encoding/binary"
 
 	"github.com/fletaio/common"
 )
 
 // tags
 var (
-	TagStaking     = []byte{1, 0}
-	tagAutoStaking = []byte{1, 1}
+	TagStaking            = []byte{1, 0}
+	tagAutoStaking        = []byte{1, 1}
+	tagPendingHyperPolicy = []byte{1, 2}
+	tagEquivocation       = []byte{1, 3}
+	tagKeyHistory         = []byte{1, 4}
+	tagJailed             = []byte{1, 5}
 )
 
 // ToStakingKey returns the staking key of the staking address
@@ -38,3 +43,44 @@ func ToAutoStakingKey(addr common.Address) []byte {
 	copy(bs[2:], addr[:])
 	return bs
 }
+
+// toEquivocationKey returns the key that marks the slashed equivocation of the height and the timeout count
+func toEquivocationKey(Height uint32, TimeoutCount uint32) []byte {
+	bs := make([]byte, 2+8)
+	copy(bs, tagEquivocation)
+	binary.LittleEndian.PutUint32(bs[2:], Height)
+	binary.LittleEndian.PutUint32(bs[6:], TimeoutCount)
+	return bs
+}
+
+// toKeyHistoryKey returns the key of the previous key hash that is used before the height
+func toKeyHistoryKey(Height uint32) []byte {
+	bs := make([]byte, 2+4)
+	copy(bs, tagKeyHistory)
+	binary.LittleEndian.PutUint32(bs[2:], Height)
+	return bs
+}
+
+// fromKeyHistoryKey returns the height if it is the key history key
+func fromKeyHistoryKey(bs []byte) (uint32, bool) {
+	if len(bs) == 2+4 && bytes.HasPrefix(bs, tagKeyHistory) {
+		return binary.LittleEndian.Uint32(bs[2:]), true
+	} else {
+		return 0, false
+	}
+}
+
+// toJailedValue returns the account data value of the height when the formulator is jailed
+func toJailedValue(Height uint32) []byte {
+	bs := make([]byte, 4)
+	binary.LittleEndian.PutUint32(bs, Height)
+	return bs
+}
+
+// fromJailedValue returns the height when the formulator is jailed if it is jailed value
+func fromJailedValue(bs []byte) (uint32, bool) {
+	if len(bs) != 4 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(bs), true
+}
@@ -159,6 +159,45 @@ func (ctx *Context) DeleteAccount(acc account.Account) error {
 	return ctx.Top().DeleteAccount(acc)
 }
 
+// merge applies updates of the child context data except events
+// It is used by the commit of snapshots and the parallel execution to make the same result
+// Locked balances and account names of the child are not applied as the commit of snapshots has never applied them,
+// so context hashes of stored blocks are not changed
+func (ctd *ContextData) merge(child *ContextData) {
+	for k, v := range child.SeqMap {
+		ctd.SeqMap[k] = v
+	}
+	for k, v := range child.AccountMap {
+		ctd.AccountMap[k] = v
+	}
+	for k, v := range child.CreatedAccountMap {
+		ctd.CreatedAccountMap[k] = v
+	}
+	for k, v := range child.DeletedAccountMap {
+		delete(ctd.AccountMap, k)
+		delete(ctd.CreatedAccountMap, k)
+		ctd.DeletedAccountMap[k] = v
+	}
+	for k, v := range child.AccountDataMap {
+		ctd.AccountDataMap[k] = v
+	}
+	for k, v := range child.DeletedAccountDataMap {
+		delete(ctd.AccountDataMap, k)
+		ctd.DeletedAccountDataMap[k] = v
+	}
+	for k, v := range child.UTXOMap {
+		ctd.UTXOMap[k] = v
+	}
+	for k, v := range child.CreatedUTXOMap {
+		ctd.CreatedUTXOMap[k] = v
+	}
+	for k, v := range child.DeletedUTXOMap {
+		delete(ctd.UTXOMap, k)
+		delete(ctd.CreatedUTXOMap, k)
+		ctd.DeletedUTXOMap[k] = v
+	}
+}
+
 // AccountDataKeys returns all data keys of the account in the context
 func (ctx *Context) AccountDataKeys(addr common.Address, Prefix []byte) ([][]byte, error) {
 	return ctx.Top().AccountDataKeys(addr, Prefix)
@@ -208,6 +247,11 @@ func (ctx *Context) Dump() string {
 	return ctx.Top().Dump()
 }
 
+// SectionHashes returns hashes of sections of the top context data of the context
+func (ctx *Context) SectionHashes() []*SectionHash {
+	return ctx.Top().SectionHashes()
+}
+
 // Snapshot push a snapshot and returns the snapshot number of it
 func (ctx *Context) Snapshot() int {
 	ctx.isLatestHash = false
@@ -233,38 +277,7 @@ func (ctx *Context) Commit(sn int) {
 		ctd := ctx.Top()
 		ctx.stack = ctx.stack[:len(ctx.stack)-1]
 		top := ctx.Top()
-		for k, v := range ctd.SeqMap {
-			top.SeqMap[k] = v
-		}
-		for k, v := range ctd.AccountMap {
-			top.AccountMap[k] = v
-		}
-		for k, v := range ctd.CreatedAccountMap {
-			top.CreatedAccountMap[k] = v
-		}
-		for k, v := range ctd.DeletedAccountMap {
-			delete(top.AccountMap, k)
-			delete(top.CreatedAccountMap, k)
-			top.DeletedAccountMap[k] = v
-		}
-		for k, v := range ctd.AccountDataMap {
-			top.AccountDataMap[k] = v
-		}
-		for k, v := range ctd.DeletedAccountDataMap {
-			delete(top.AccountDataMap, k)
-			top.DeletedAccountDataMap[k] = v
-		}
-		for k, v := range ctd.UTXOMap {
-			top.UTXOMap[k] = v
-		}
-		for k, v := range ctd.CreatedUTXOMap {
-			top.CreatedUTXOMap[k] = v
-		}
-		for k, v := range ctd.DeletedUTXOMap {
-			delete(top.UTXOMap, k)
-			delete(top.CreatedUTXOMap, k)
-			top.DeletedUTXOMap[k] = v
-		}
+		top.merge(ctd)
 		for _, v := range ctd.Events {
 			top.Events = append(top.Events, v)
 		}
@@ -684,221 +697,270 @@ func (ctd *ContextData) EmitEvent(e event.Event) error {
 	return nil
 }
 
+// contextHashSections is the order of sections of the context data in the context hash
+var contextHashSections = []string{
+	"ChainCoord",
+	"SeqMap",
+	"LockedBalances",
+	"DeletedLockedBalances",
+	"AccountMap",
+	"CreatedAccountMap",
+	"DeletedAccountMap",
+	"AccountNameMap",
+	"CreatedAccountNameMap",
+	"DeletedAccountNameMap",
+	"AccountDataMap",
+	"DeletedAccountDataMap",
+	"UTXOMap",
+	"CreatedUTXOMap",
+	"DeletedUTXOMap",
+	"Events",
+}
+
 // Hash returns the hash value of it
 func (ctd *ContextData) Hash() hash.Hash256 {
 	var buffer bytes.Buffer
-
-	buffer.WriteString("ChainCoord")
-	if _, err := ctd.loader.ChainCoord().WriteTo(&buffer); err != nil {
-		panic(err)
+	for _, name := range contextHashSections {
+		buffer.WriteString(name)
+		ctd.writeHashSection(&buffer, name)
 	}
-	buffer.WriteString("SeqMap")
-	if len(ctd.SeqMap) > 0 {
-		keys := []common.Address{}
-		for k := range ctd.SeqMap {
-			keys = append(keys, k)
-		}
-		sort.Sort(addressSlice(keys))
-		for _, k := range keys {
-			v := ctd.SeqMap[k]
-			if _, err := k.WriteTo(&buffer); err != nil {
-				panic(err)
+	return hash.DoubleHash(buffer.Bytes())
+}
+
+// SectionHash is the hash of a section of the context data
+type SectionHash struct {
+	Name string
+	Hash hash.Hash256
+}
+
+// SectionHashes returns hashes of sections of the context data in the order of the context hash
+// It is used to find the diverged section when context hashes of nodes are not matched
+func (ctd *ContextData) SectionHashes() []*SectionHash {
+	list := make([]*SectionHash, 0, len(contextHashSections))
+	for _, name := range contextHashSections {
+		var buffer bytes.Buffer
+		ctd.writeHashSection(&buffer, name)
+		list = append(list, &SectionHash{
+			Name: name,
+			Hash: hash.Hash(buffer.Bytes()),
+		})
+	}
+	return list
+}
+
+func (ctd *ContextData) writeHashSection(buffer *bytes.Buffer, name string) {
+	switch name {
+	case "ChainCoord":
+		if _, err := ctd.loader.ChainCoord().WriteTo(buffer); err != nil {
+			panic(err)
+		}
+	case "SeqMap":
+		if len(ctd.SeqMap) > 0 {
+			keys := []common.Address{}
+			for k := range ctd.SeqMap {
+				keys = append(keys, k)
 			}
-			if _, err := util.WriteUint64(&buffer, v); err != nil {
-				panic(err)
+			sort.Sort(addressSlice(keys))
+			for _, k := range keys {
+				v := ctd.SeqMap[k]
+				if _, err := k.WriteTo(buffer); err != nil {
+					panic(err)
+				}
+				if _, err := util.WriteUint64(buffer, v); err != nil {
+					panic(err)
+				}
 			}
 		}
-	}
-	buffer.WriteString("LockedBalances")
-	if len(ctd.LockedBalances) > 0 {
-		for _, v := range ctd.LockedBalances {
-			if _, err := v.WriteTo(&buffer); err != nil {
-				panic(err)
+	case "LockedBalances":
+		if len(ctd.LockedBalances) > 0 {
+			for _, v := range ctd.LockedBalances {
+				if _, err := v.WriteTo(buffer); err != nil {
+					panic(err)
+				}
 			}
 		}
-	}
-	buffer.WriteString("DeletedLockedBalances")
-	if len(ctd.DeletedLockedBalances) > 0 {
-		for _, v := range ctd.DeletedLockedBalances {
-			if _, err := v.WriteTo(&buffer); err != nil {
-				panic(err)
+	case "DeletedLockedBalances":
+		if len(ctd.DeletedLockedBalances) > 0 {
+			for _, v := range ctd.DeletedLockedBalances {
+				if _, err := v.WriteTo(buffer); err != nil {
+					panic(err)
+				}
 			}
 		}
-	}
-	buffer.WriteString("AccountMap")
-	if len(ctd.AccountMap) > 0 {
-		keys := []common.Address{}
-		for k := range ctd.AccountMap {
-			keys = append(keys, k)
-		}
-		sort.Sort(addressSlice(keys))
-		for _, k := range keys {
-			v := ctd.AccountMap[k]
-			if _, err := k.WriteTo(&buffer); err != nil {
-				panic(err)
+	case "AccountMap":
+		if len(ctd.AccountMap) > 0 {
+			keys := []common.Address{}
+			for k := range ctd.AccountMap {
+				keys = append(keys, k)
 			}
-			if _, err := v.WriteTo(&buffer); err != nil {
-				panic(err)
+			sort.Sort(addressSlice(keys))
+			for _, k := range keys {
+				v := ctd.AccountMap[k]
+				if _, err := k.WriteTo(buffer); err != nil {
+					panic(err)
+				}
+				if _, err := v.WriteTo(buffer); err != nil {
+					panic(err)
+				}
 			}
 		}
-	}
-	buffer.WriteString("CreatedAccountMap")
-	if len(ctd.CreatedAccountMap) > 0 {
-		keys := []common.Address{}
-		for k := range ctd.CreatedAccountMap {
-			keys = append(keys, k)
-		}
-		sort.Sort(addressSlice(keys))
-		for _, k := range keys {
-			v := ctd.CreatedAccountMap[k]
-			if _, err := k.WriteTo(&buffer); err != nil {
-				panic(err)
+	case "CreatedAccountMap":
+		if len(ctd.CreatedAccountMap) > 0 {
+			keys := []common.Address{}
+			for k := range ctd.CreatedAccountMap {
+				keys = append(keys, k)
 			}
-			if _, err := v.WriteTo(&buffer); err != nil {
-				panic(err)
+			sort.Sort(addressSlice(keys))
+			for _, k := range keys {
+				v := ctd.CreatedAccountMap[k]
+				if _, err := k.WriteTo(buffer); err != nil {
+					panic(err)
+				}
+				if _, err := v.WriteTo(buffer); err != nil {
+					panic(err)
+				}
 			}
 		}
-	}
-	buffer.WriteString("DeletedAccountMap")
-	if len(ctd.DeletedAccountMap) > 0 {
-		keys := []common.Address{}
-		for k := range ctd.DeletedAccountMap {
-			keys = append(keys, k)
-		}
-		sort.Sort(addressSlice(keys))
-		for _, k := range keys {
-			if _, err := k.WriteTo(&buffer); err != nil {
-				panic(err)
+	case "DeletedAccountMap":
+		if len(ctd.DeletedAccountMap) > 0 {
+			keys := []common.Address{}
+			for k := range ctd.DeletedAccountMap {
+				keys = append(keys, k)
+			}
+			sort.Sort(addressSlice(keys))
+			for _, k := range keys {
+				if _, err := k.WriteTo(buffer); err != nil {
+					panic(err)
+				}
 			}
 		}
-	}
-	buffer.WriteString("AccountNameMap")
-	if len(ctd.AccountNameMap) > 0 {
-		keys := []string{}
-		for k := range ctd.AccountNameMap {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-		for _, k := range keys {
-			v := ctd.AccountNameMap[k]
-			if _, err := buffer.WriteString(k); err != nil {
-				panic(err)
+	case "AccountNameMap":
+		if len(ctd.AccountNameMap) > 0 {
+			keys := []string{}
+			for k := range ctd.AccountNameMap {
+				keys = append(keys, k)
 			}
-			if _, err := v.WriteTo(&buffer); err != nil {
-				panic(err)
+			sort.Strings(keys)
+			for _, k := range keys {
+				v := ctd.AccountNameMap[k]
+				if _, err := buffer.WriteString(k); err != nil {
+					panic(err)
+				}
+				if _, err := v.WriteTo(buffer); err != nil {
+					panic(err)
+				}
 			}
 		}
-	}
-	buffer.WriteString("CreatedAccountNameMap")
-	if len(ctd.CreatedAccountNameMap) > 0 {
-		keys := []string{}
-		for k := range ctd.CreatedAccountNameMap {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-		for _, k := range keys {
-			v := ctd.CreatedAccountNameMap[k]
-			if _, err := buffer.WriteString(k); err != nil {
-				panic(err)
+	case "CreatedAccountNameMap":
+		if len(ctd.CreatedAccountNameMap) > 0 {
+			keys := []string{}
+			for k := range ctd.CreatedAccountNameMap {
+				keys = append(keys, k)
 			}
-			if _, err := v.WriteTo(&buffer); err != nil {
-				panic(err)
+			sort.Strings(keys)
+			for _, k := range keys {
+				v := ctd.CreatedAccountNameMap[k]
+				if _, err := buffer.WriteString(k); err != nil {
+					panic(err)
+				}
+				if _, err := v.WriteTo(buffer); err != nil {
+					panic(err)
+				}
 			}
 		}
-	}
-	buffer.WriteString("DeletedAccountNameMap")
-	if len(ctd.DeletedAccountNameMap) > 0 {
-		keys := []string{}
-		for k := range ctd.DeletedAccountNameMap {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-		for _, k := range keys {
-			if _, err := buffer.WriteString(k); err != nil {
-				panic(err)
+	case "DeletedAccountNameMap":
+		if len(ctd.DeletedAccountNameMap) > 0 {
+			keys := []string{}
+			for k := range ctd.DeletedAccountNameMap {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				if _, err := buffer.WriteString(k); err != nil {
+					panic(err)
+				}
 			}
 		}
-	}
-	buffer.WriteString("AccountDataMap")
-	if len(ctd.AccountDataMap) > 0 {
-		keys := []string{}
-		for k := range ctd.AccountDataMap {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-		for _, k := range keys {
-			v := ctd.AccountDataMap[k]
-			buffer.WriteString(k)
-			buffer.Write(v)
-		}
-	}
-	buffer.WriteString("DeletedAccountDataMap")
-	if len(ctd.DeletedAccountDataMap) > 0 {
-		keys := []string{}
-		for k := range ctd.DeletedAccountDataMap {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-		for _, k := range keys {
-			buffer.WriteString(k)
-		}
-	}
-	buffer.WriteString("UTXOMap")
-	if len(ctd.UTXOMap) > 0 {
-		keys := []uint64{}
-		for k := range ctd.UTXOMap {
-			keys = append(keys, k)
-		}
-		sort.Sort(uint64Slice(keys))
-		for _, k := range keys {
-			v := ctd.UTXOMap[k]
-			if _, err := util.WriteUint64(&buffer, k); err != nil {
-				panic(err)
+	case "AccountDataMap":
+		if len(ctd.AccountDataMap) > 0 {
+			keys := []string{}
+			for k := range ctd.AccountDataMap {
+				keys = append(keys, k)
 			}
-			if _, err := v.WriteTo(&buffer); err != nil {
-				panic(err)
+			sort.Strings(keys)
+			for _, k := range keys {
+				v := ctd.AccountDataMap[k]
+				buffer.WriteString(k)
+				buffer.Write(v)
 			}
 		}
-	}
-	buffer.WriteString("CreatedUTXOMap")
-	if len(ctd.CreatedUTXOMap) > 0 {
-		keys := []uint64{}
-		for k := range ctd.CreatedUTXOMap {
-			keys = append(keys, k)
+	case "DeletedAccountDataMap":
+		if len(ctd.DeletedAccountDataMap) > 0 {
+			keys := []string{}
+			for k := range ctd.DeletedAccountDataMap {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				buffer.WriteString(k)
+			}
 		}
-		sort.Sort(uint64Slice(keys))
-		for _, k := range keys {
-			v := ctd.CreatedUTXOMap[k]
-			if _, err := util.WriteUint64(&buffer, k); err != nil {
-				panic(err)
+	case "UTXOMap":
+		if len(ctd.UTXOMap) > 0 {
+			keys := []uint64{}
+			for k := range ctd.UTXOMap {
+				keys = append(keys, k)
 			}
-			if _, err := v.WriteTo(&buffer); err != nil {
-				panic(err)
+			sort.Sort(uint64Slice(keys))
+			for _, k := range keys {
+				v := ctd.UTXOMap[k]
+				if _, err := util.WriteUint64(buffer, k); err != nil {
+					panic(err)
+				}
+				if _, err := v.WriteTo(buffer); err != nil {
+					panic(err)
+				}
 			}
 		}
-	}
-	buffer.WriteString("DeletedUTXOMap")
-	if len(ctd.DeletedUTXOMap) > 0 {
-		keys := []uint64{}
-		for k := range ctd.DeletedUTXOMap {
-			keys = append(keys, k)
+	case "CreatedUTXOMap":
+		if len(ctd.CreatedUTXOMap) > 0 {
+			keys := []uint64{}
+			for k := range ctd.CreatedUTXOMap {
+				keys = append(keys, k)
+			}
+			sort.Sort(uint64Slice(keys))
+			for _, k := range keys {
+				v := ctd.CreatedUTXOMap[k]
+				if _, err := util.WriteUint64(buffer, k); err != nil {
+					panic(err)
+				}
+				if _, err := v.WriteTo(buffer); err != nil {
+					panic(err)
+				}
+			}
 		}
-		sort.Sort(uint64Slice(keys))
-		for _, k := range keys {
-			if _, err := util.WriteUint64(&buffer, k); err != nil {
-				panic(err)
+	case "DeletedUTXOMap":
+		if len(ctd.DeletedUTXOMap) > 0 {
+			keys := []uint64{}
+			for k := range ctd.DeletedUTXOMap {
+				keys = append(keys, k)
+			}
+			sort.Sort(uint64Slice(keys))
+			for _, k := range keys {
+				if _, err := util.WriteUint64(buffer, k); err != nil {
+					panic(err)
+				}
 			}
 		}
-	}
-	buffer.WriteString("Events")
-	if len(ctd.Events) > 0 {
-		for _, e := range ctd.Events {
-			if _, err := e.WriteTo(&buffer); err != nil {
-				panic(err)
+	case "Events":
+		if len(ctd.Events) > 0 {
+			for _, e := range ctd.Events {
+				if _, err := e.WriteTo(buffer); err != nil {
+					panic(err)
+				}
 			}
 		}
 	}
-	return hash.DoubleHash(buffer.Bytes())
 }
 
 // Dump prints the context data
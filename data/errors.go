@@ -19,4 +19,6 @@ var (
 	ErrInvalidChainCoordinate = errors.New("invalid chain coordinate")
 	ErrUnknownEventType       = errors.New("unknown event type")
 	ErrInvalidAccountName     = errors.New("invalid account name")
+	ErrDirtyContext           = errors.New("dirty context")
+	ErrInvalidPriorityFee     = errors.New("invalid priority fee")
 )
@@ -0,0 +1,308 @@
+package data
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/common/util"
+	"github.com/fletaio/core/account"
+	"github.com/fletaio/core/transaction"
+	"github.com/fletaio/framework/chain"
+)
+
+// parallelExecuteThreshold is the minimum number of transactions that are executed in parallel
+const parallelExecuteThreshold = 16
+
+// ExecuteTransactions executes transactions in parallel and applies results to the context in the order of them
+// Each transaction is executed on a separated context data over the context and the result that reads data updated by previous transactions is executed again,
+// so the result is same with executing them sequentially by Execute in snapshots
+// Transactions less than the threshold are executed one by one on the same way to make the same result for the generation and the validation of the block
+// If bSkipFailed is true, failed transactions are not applied and their errors are returned at their positions and indexes of following transactions are shifted,
+// otherwise the first error is returned
+func (tran *Transactor) ExecuteTransactions(ctx *Context, txs []transaction.Transaction, Height uint32, Index uint16, bSkipFailed bool) ([]error, error) {
+	// the top is not updated by reads of layers during the execution
+	top := ctx.Top()
+	top.isTop = false
+	defer func() {
+		top.isTop = true
+		ctx.isLatestHash = false
+	}()
+
+	var lock sync.Mutex
+	results := make([]*layerResult, len(txs))
+	if len(txs) >= parallelExecuteThreshold {
+		idxCh := make(chan int, len(txs))
+		for i := range txs {
+			idxCh <- i
+		}
+		close(idxCh)
+
+		var wg sync.WaitGroup
+		for i := 0; i < runtime.NumCPU(); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for idx := range idxCh {
+					results[idx] = tran.executeLayer(ctx, &lock, txs[idx], Height, Index+uint16(idx))
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	errs := make([]error, len(txs))
+	writtenMap := map[string]bool{}
+	idx := Index
+	for i, tx := range txs {
+		res := results[i]
+		if res == nil || res.index != idx || res.isConflicted(writtenMap) {
+			res = tran.executeLayer(ctx, &lock, tx, Height, idx)
+		}
+		if res.err != nil {
+			if !bSkipFailed {
+				return nil, res.err
+			}
+			errs[i] = res.err
+			continue
+		}
+		top.applyLayer(res.ctd)
+		res.ctd.writtenKeys(writtenMap)
+		idx++
+	}
+	return errs, nil
+}
+
+type layerResult struct {
+	ctd     *ContextData
+	index   uint16
+	readMap map[string]bool
+	err     error
+}
+
+func (res *layerResult) isConflicted(writtenMap map[string]bool) bool {
+	if len(writtenMap) == 0 {
+		return false
+	}
+	for k := range res.readMap {
+		if writtenMap[k] {
+			return true
+		}
+	}
+	return false
+}
+
+func (tran *Transactor) executeLayer(loader Loader, lock sync.Locker, tx transaction.Transaction, Height uint32, Index uint16) *layerResult {
+	rl := &recordLoader{
+		loader:  loader,
+		lock:    lock,
+		readMap: map[string]bool{},
+	}
+	lctx := NewContext(rl)
+	res := &layerResult{
+		index:   Index,
+		readMap: rl.readMap,
+	}
+	if _, err := tran.Execute(lctx, tx, &common.Coordinate{Height: Height, Index: Index}); err != nil {
+		res.err = err
+	} else if lctx.StackSize() > 1 {
+		res.err = ErrDirtyContext
+	} else {
+		res.ctd = lctx.Top()
+	}
+	return res
+}
+
+// applyLayer applies the layer to the context data as same as the commit of the snapshot that executes the transaction of the layer
+// Events are indexed again because the layer doesn't know the event index of the context data
+func (ctd *ContextData) applyLayer(layer *ContextData) {
+	ctd.merge(layer)
+	for _, e := range layer.Events {
+		e.SetIndex(ctd.EventIndex)
+		ctd.EventIndex++
+		ctd.Events = append(ctd.Events, e)
+	}
+}
+
+// writtenKeys adds keys of the data that is updated or loaded by the layer
+// Loaded accounts are included because they can be updated in place
+func (ctd *ContextData) writtenKeys(keyMap map[string]bool) {
+	for k := range ctd.SeqMap {
+		keyMap[toAddressLayerKey(k)] = true
+	}
+	for k := range ctd.AccountMap {
+		keyMap[toAddressLayerKey(k)] = true
+	}
+	for k := range ctd.CreatedAccountMap {
+		keyMap[toAddressLayerKey(k)] = true
+	}
+	for k := range ctd.DeletedAccountMap {
+		keyMap[toAddressLayerKey(k)] = true
+	}
+	for k := range ctd.AccountNameMap {
+		keyMap[toNameLayerKey(k)] = true
+	}
+	for k := range ctd.CreatedAccountNameMap {
+		keyMap[toNameLayerKey(k)] = true
+	}
+	for k := range ctd.DeletedAccountNameMap {
+		keyMap[toNameLayerKey(k)] = true
+	}
+	for k := range ctd.AccountDataMap {
+		keyMap[toAccountDataLayerKey(k)] = true
+	}
+	for k := range ctd.DeletedAccountDataMap {
+		keyMap[toAccountDataLayerKey(k)] = true
+	}
+	for k := range ctd.UTXOMap {
+		keyMap[toUTXOLayerKey(k)] = true
+	}
+	for k := range ctd.CreatedUTXOMap {
+		keyMap[toUTXOLayerKey(k)] = true
+	}
+	for k := range ctd.DeletedUTXOMap {
+		keyMap[toUTXOLayerKey(k)] = true
+	}
+}
+
+func toAddressLayerKey(addr common.Address) string {
+	return "a" + string(addr[:])
+}
+
+func toNameLayerKey(Name string) string {
+	return "n" + Name
+}
+
+func toAccountDataLayerKey(key string) string {
+	var addr common.Address
+	return "a" + key[:len(addr)]
+}
+
+func toUTXOLayerKey(id uint64) string {
+	return "u" + string(util.Uint64ToBytes(id))
+}
+
+// recordLoader records keys of the data that is read by the layer
+// Reads of the loader are serialized by the lock because the loader can update its cache
+type recordLoader struct {
+	loader  Loader
+	lock    sync.Locker
+	readMap map[string]bool
+}
+
+// ChainCoord returns the coordinate of the target chain
+func (rl *recordLoader) ChainCoord() *common.Coordinate {
+	return rl.loader.ChainCoord()
+}
+
+// Accounter returns the accounter of the target chain
+func (rl *recordLoader) Accounter() *Accounter {
+	return rl.loader.Accounter()
+}
+
+// Transactor returns the transactor of the target chain
+func (rl *recordLoader) Transactor() *Transactor {
+	return rl.loader.Transactor()
+}
+
+// Provider returns the provider of the target chain
+func (rl *recordLoader) Provider() chain.Provider {
+	return rl.loader.Provider()
+}
+
+// Eventer returns the eventer of the target chain
+func (rl *recordLoader) Eventer() *Eventer {
+	return rl.loader.Eventer()
+}
+
+// TargetHeight returns the target height of the loader
+func (rl *recordLoader) TargetHeight() uint32 {
+	return rl.loader.TargetHeight()
+}
+
+// LastHash returns the last hash of the loader
+func (rl *recordLoader) LastHash() hash.Hash256 {
+	return rl.loader.LastHash()
+}
+
+// Seq returns the sequence of the account
+func (rl *recordLoader) Seq(addr common.Address) uint64 {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	rl.readMap[toAddressLayerKey(addr)] = true
+	return rl.loader.Seq(addr)
+}
+
+// Account returns the account instance of the address
+func (rl *recordLoader) Account(addr common.Address) (account.Account, error) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	rl.readMap[toAddressLayerKey(addr)] = true
+	return rl.loader.Account(addr)
+}
+
+// AddressByName returns the account address of the name
+func (rl *recordLoader) AddressByName(Name string) (common.Address, error) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	rl.readMap[toNameLayerKey(Name)] = true
+	return rl.loader.AddressByName(Name)
+}
+
+// IsExistAccount checks that the account of the address is exist or not
+func (rl *recordLoader) IsExistAccount(addr common.Address) (bool, error) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	rl.readMap[toAddressLayerKey(addr)] = true
+	return rl.loader.IsExistAccount(addr)
+}
+
+// IsExistAccountName checks that the account of the name is exist or not
+func (rl *recordLoader) IsExistAccountName(Name string) (bool, error) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	rl.readMap[toNameLayerKey(Name)] = true
+	return rl.loader.IsExistAccountName(Name)
+}
+
+// AccountData returns the account data
+func (rl *recordLoader) AccountData(addr common.Address, name []byte) []byte {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	rl.readMap[toAddressLayerKey(addr)] = true
+	return rl.loader.AccountData(addr, name)
+}
+
+// AccountDataKeys returns all data keys of the account
+func (rl *recordLoader) AccountDataKeys(addr common.Address, Prefix []byte) ([][]byte, error) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	rl.readMap[toAddressLayerKey(addr)] = true
+	return rl.loader.AccountDataKeys(addr, Prefix)
+}
+
+// IsExistUTXO checks that the utxo of the id is exist or not
+func (rl *recordLoader) IsExistUTXO(id uint64) (bool, error) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	rl.readMap[toUTXOLayerKey(id)] = true
+	return rl.loader.IsExistUTXO(id)
+}
+
+// UTXO returns the UTXO
+func (rl *recordLoader) UTXO(id uint64) (*transaction.UTXO, error) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	rl.readMap[toUTXOLayerKey(id)] = true
+	return rl.loader.UTXO(id)
+}
@@ -0,0 +1,288 @@
+package data
+
+import (
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/common/util"
+	"github.com/fletaio/core/account"
+	"github.com/fletaio/core/amount"
+	"github.com/fletaio/core/event"
+	"github.com/fletaio/core/transaction"
+)
+
+func init() {
+	RegisterAccount("test.Account", func(t account.Type) account.Account {
+		return &testAccount{
+			Base: account.Base{
+				Type_:    t,
+				Balance_: amount.NewCoinAmount(0, 0),
+			},
+		}
+	}, func(loader Loader, a account.Account, signers []common.PublicHash) error {
+		return nil
+	})
+	RegisterTransaction("test.Transaction", func(t transaction.Type) transaction.Transaction {
+		return &testTransaction{
+			Base: transaction.Base{
+				Type_: t,
+			},
+		}
+	}, func(loader Loader, t transaction.Transaction, signers []common.PublicHash) error {
+		return nil
+	}, executeTestTransaction)
+}
+
+const (
+	testOpTransfer = iota
+	testOpCreateAccount
+	testOpDeleteAccount
+	testOpSetAccountData
+	testOpLockBalance
+	testOpCreateUTXO
+	testOpDeleteUTXO
+	testOpCount
+)
+
+type testAccount struct {
+	account.Base
+}
+
+func (acc *testAccount) Clone() account.Account {
+	return &testAccount{
+		Base: account.Base{
+			Type_:    acc.Type_,
+			Address_: acc.Address_,
+			Name_:    acc.Name_,
+			Balance_: acc.Balance(),
+		},
+	}
+}
+
+func (acc *testAccount) MarshalJSON() ([]byte, error) {
+	return []byte(`{}`), nil
+}
+
+type testEvent struct {
+	event.Base
+}
+
+func (e *testEvent) MarshalJSON() ([]byte, error) {
+	return []byte(`{}`), nil
+}
+
+type testTransaction struct {
+	transaction.Base
+	Op     uint8
+	From   common.Address
+	To     common.Address
+	Amount *amount.Amount
+	Value  []byte
+	ID     uint64
+	Height uint32
+}
+
+func (tx *testTransaction) IsUTXO() bool {
+	return false
+}
+
+func (tx *testTransaction) Hash() hash.Hash256 {
+	return hash.DoubleHashByWriterTo(tx)
+}
+
+func (tx *testTransaction) WriteTo(w io.Writer) (int64, error) {
+	var wrote int64
+	if n, err := tx.Base.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteUint8(w, tx.Op); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	return wrote, nil
+}
+
+func (tx *testTransaction) MarshalJSON() ([]byte, error) {
+	return []byte(`{}`), nil
+}
+
+func executeTestTransaction(ctx *Context, Fee *amount.Amount, t transaction.Transaction, coord *common.Coordinate) (interface{}, error) {
+	tx := t.(*testTransaction)
+	sn := ctx.Snapshot()
+	defer ctx.Revert(sn)
+
+	switch tx.Op {
+	case testOpTransfer:
+		ctx.AddSeq(tx.From)
+		from, err := ctx.Account(tx.From)
+		if err != nil {
+			return nil, err
+		}
+		if err := from.SubBalance(tx.Amount); err != nil {
+			return nil, err
+		}
+		to, err := ctx.Account(tx.To)
+		if err != nil {
+			return nil, err
+		}
+		to.AddBalance(tx.Amount)
+	case testOpCreateAccount:
+		if err := ctx.CreateAccount(&testAccount{
+			Base: account.Base{
+				Type_:    1,
+				Address_: tx.To,
+				Name_:    testAccountName(tx.To),
+				Balance_: tx.Amount.Clone(),
+			},
+		}); err != nil {
+			return nil, err
+		}
+	case testOpDeleteAccount:
+		acc, err := ctx.Account(tx.From)
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.DeleteAccount(acc); err != nil {
+			return nil, err
+		}
+	case testOpSetAccountData:
+		if _, err := ctx.Account(tx.From); err != nil {
+			return nil, err
+		}
+		ctx.SetAccountData(tx.From, []byte("data"), tx.Value)
+	case testOpLockBalance:
+		from, err := ctx.Account(tx.From)
+		if err != nil {
+			return nil, err
+		}
+		if err := from.SubBalance(tx.Amount); err != nil {
+			return nil, err
+		}
+		ctx.AddLockedBalance(tx.To, tx.Amount, tx.Height)
+	case testOpCreateUTXO:
+		vout := transaction.NewTxOut()
+		vout.Amount = tx.Amount.Clone()
+		if err := ctx.CreateUTXO(tx.ID, vout); err != nil {
+			return nil, err
+		}
+	case testOpDeleteUTXO:
+		if err := ctx.DeleteUTXO(tx.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ctx.EmitEvent(&testEvent{
+		Base: event.Base{
+			Coord_: coord.Clone(),
+			Type_:  1,
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	ctx.Commit(sn)
+	return nil, nil
+}
+
+func testAddress(i int) common.Address {
+	var addr common.Address
+	addr[0] = 1
+	addr[1] = byte(i)
+	return addr
+}
+
+func testAccountName(addr common.Address) string {
+	return "test" + string('a'+rune(addr[1]))
+}
+
+func Test_ExecuteTransactions(t *testing.T) {
+	coord := common.NewCoordinate(0, 0)
+	act := NewAccounter(coord)
+	if err := act.RegisterType("test.Account", 1); err != nil {
+		t.Fatal(err)
+	}
+	tran := NewTransactor(coord)
+	if err := tran.RegisterType("test.Transaction", 1, amount.NewCoinAmount(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	base := NewContext(NewEmptyLoader(coord, act, tran, NewEventer(coord)))
+	for i := 0; i < 4; i++ {
+		addr := testAddress(i)
+		if err := base.CreateAccount(&testAccount{
+			Base: account.Base{
+				Type_:    1,
+				Address_: addr,
+				Name_:    testAccountName(addr),
+				Balance_: amount.NewCoinAmount(100, 0),
+			},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		base.SetAccountData(addr, []byte("data"), []byte{byte(i)})
+	}
+	for i := 0; i < 3; i++ {
+		vout := transaction.NewTxOut()
+		vout.Amount = amount.NewCoinAmount(1, 0)
+		if err := base.CreateUTXO(uint64(i), vout); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for seed := int64(0); seed < 20; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		txs := make([]transaction.Transaction, 8+r.Intn(40))
+		for i := range txs {
+			tx := &testTransaction{
+				Base: transaction.Base{
+					Type_:      1,
+					Timestamp_: uint64(i),
+				},
+				Op:     uint8(r.Intn(testOpCount)),
+				From:   testAddress(r.Intn(6)),
+				To:     testAddress(r.Intn(6)),
+				Amount: amount.NewCoinAmount(uint64(r.Intn(60)), 0),
+				ID:     uint64(r.Intn(6)),
+				Height: uint32(r.Intn(3)),
+			}
+			if r.Intn(2) == 0 {
+				tx.Value = []byte{byte(r.Intn(256))}
+			}
+			txs[i] = tx
+		}
+
+		ctx := base.NextContext(hash.Hash([]byte("next")))
+		failed := map[int]bool{}
+		idx := uint16(0)
+		for i, tx := range txs {
+			sn := ctx.Snapshot()
+			if _, err := tran.Execute(ctx, tx, &common.Coordinate{Height: 1, Index: idx}); err != nil {
+				ctx.Revert(sn)
+				failed[i] = true
+				continue
+			}
+			ctx.Commit(sn)
+			idx++
+		}
+
+		lctx := base.NextContext(hash.Hash([]byte("next")))
+		errs, err := tran.ExecuteTransactions(lctx, txs, 1, 0, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, err := range errs {
+			if (err != nil) != failed[i] {
+				t.Fatal("the result of the transaction is not same", seed, i, err)
+			}
+		}
+		if !ctx.Hash().Equal(lctx.Hash()) {
+			t.Fatal("the context hash is not same", seed, len(txs), "\n", ctx.Dump(), "\n", lctx.Dump())
+		}
+	}
+}
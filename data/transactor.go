@@ -38,6 +38,19 @@ func (tran *Transactor) Validate(loader Loader, tx transaction.Transaction, sign
 		return ErrInvalidChainCoordinate
 	}
 
+	// only the expiry is checked because the transaction that is not valid yet can be pooled until its not before height
+	// the not before height is checked by the generation and the validation of blocks and the execution of the transaction
+	// the timestamp of the block is checked by the kernel because the loader doesn't know it
+	if transaction.IsExpired(tx, loader.TargetHeight(), 0) {
+		return transaction.ErrExpiredTransaction
+	}
+
+	if ptx, is := tx.(transaction.PriorityFeeTransaction); is {
+		if fee := ptx.PriorityFee(); fee == nil || fee.Less(amount.NewCoinAmount(0, 0)) {
+			return ErrInvalidPriorityFee
+		}
+	}
+
 	if item, has := tran.handlerTypeMap[tx.Type()]; !has {
 		return ErrNotExistHandler
 	} else {
@@ -54,11 +67,14 @@ func (tran *Transactor) Execute(ctx *Context, tx transaction.Transaction, coord
 	if !tran.chainCoord.Equal(ctx.ChainCoord()) {
 		return nil, ErrInvalidChainCoordinate
 	}
+	if err := transaction.CheckWindow(tx, coord.Height, 0); err != nil {
+		return nil, err
+	}
 
 	if item, has := tran.handlerTypeMap[t]; !has {
 		return nil, ErrNotExistHandler
 	} else {
-		if ret, err := item.Executor(ctx, tran.feeMap[t].Clone(), tx, coord); err != nil {
+		if ret, err := item.Executor(ctx, tran.Fee(tx), tx, coord); err != nil {
 			return nil, err
 		} else {
 			return ret, nil
@@ -66,6 +82,20 @@ func (tran *Transactor) Execute(ctx *Context, tx transaction.Transaction, coord
 	}
 }
 
+// Fee returns the fee of the transaction that is the sum of the fee of the type and the priority fee of it
+func (tran *Transactor) Fee(tx transaction.Transaction) *amount.Amount {
+	fee := amount.NewCoinAmount(0, 0)
+	if v, has := tran.feeMap[tx.Type()]; has {
+		fee = v.Clone()
+	}
+	if ptx, is := tx.(transaction.PriorityFeeTransaction); is {
+		if v := ptx.PriorityFee(); v != nil {
+			fee = fee.Add(v)
+		}
+	}
+	return fee
+}
+
 // RegisterType add the transaction type with handler loaded by the name from the global transaction registry
 func (tran *Transactor) RegisterType(Name string, t transaction.Type, Fee *amount.Amount) error {
 	item, err := loadTransactionHandler(Name)
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dgraph-io/badger"
+)
+
+// BadgerDB is a DB that is based on the badger
+// All updates are executed with FileSync option
+type BadgerDB struct {
+	db     *badger.DB
+	ticker *time.Ticker
+}
+
+// NewBadgerDB returns a BadgerDB
+func NewBadgerDB(path string, bRecover bool) (*BadgerDB, error) {
+	opts := badger.DefaultOptions
+	opts.Dir = path
+	opts.ValueDir = path
+	opts.Truncate = bRecover
+	opts.SyncWrites = true
+	lockfilePath := filepath.Join(opts.Dir, "LOCK")
+	os.MkdirAll(path, os.ModeDir)
+
+	os.Remove(lockfilePath)
+
+	bdb, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	{
+	again:
+		if err := bdb.RunValueLogGC(0.7); err != nil {
+		} else {
+			goto again
+		}
+	}
+
+	ticker := time.NewTicker(5 * time.Minute)
+	go func() {
+		for range ticker.C {
+		again:
+			if err := bdb.RunValueLogGC(0.7); err != nil {
+			} else {
+				goto again
+			}
+		}
+	}()
+
+	return &BadgerDB{
+		db:     bdb,
+		ticker: ticker,
+	}, nil
+}
+
+// View executes the read only transaction
+func (db *BadgerDB) View(fn func(txn Txn) error) error {
+	return db.db.View(func(txn *badger.Txn) error {
+		return fn(&badgerTxn{txn: txn})
+	})
+}
+
+// Update executes the read write transaction
+func (db *BadgerDB) Update(fn func(txn Txn) error) error {
+	return db.db.Update(func(txn *badger.Txn) error {
+		return fn(&badgerTxn{txn: txn})
+	})
+}
+
+// Compact reclaims the space of the deleted values from the value log
+func (db *BadgerDB) Compact() error {
+	for {
+		if err := db.db.RunValueLogGC(0.5); err != nil {
+			if err == badger.ErrNoRewrite {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Close terminates the db
+func (db *BadgerDB) Close() error {
+	db.ticker.Stop()
+	return db.db.Close()
+}
+
+type badgerTxn struct {
+	txn *badger.Txn
+}
+
+// Get returns the value of the key
+func (txn *badgerTxn) Get(key []byte) ([]byte, error) {
+	item, err := txn.txn.Get(key)
+	if err != nil {
+		if err == badger.ErrKeyNotFound {
+			return nil, ErrNotExistKey
+		} else {
+			return nil, err
+		}
+	}
+	if item.IsDeletedOrExpired() {
+		return nil, ErrNotExistKey
+	}
+	value, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return value, nil
+}
+
+// Set updates the value of the key
+func (txn *badgerTxn) Set(key []byte, value []byte) error {
+	return txn.txn.Set(key, value)
+}
+
+// Delete deletes the key
+func (txn *badgerTxn) Delete(key []byte) error {
+	return txn.txn.Delete(key)
+}
+
+// Iterate calls the function with keys that have the prefix from the begin key by the ascending order
+// The iteration is stopped without an error when the function returns ErrStopIteration
+func (txn *badgerTxn) Iterate(begin []byte, prefix []byte, fn func(key []byte, value []byte) error) error {
+	it := txn.txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+	for it.Seek(begin); it.ValidForPrefix(prefix); it.Next() {
+		item := it.Item()
+		value, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			value = []byte{}
+		}
+		if err := fn(item.KeyCopy(nil), value); err != nil {
+			if err == ErrStopIteration {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package db
+
+// DB is a key-value storage that executes reads and writes in atomic transactions
+type DB interface {
+	View(fn func(txn Txn) error) error
+	Update(fn func(txn Txn) error) error
+	Compact() error
+	Close() error
+}
+
+// Txn is a transaction of the DB
+// Updates of the transaction are visible to the reads of it and applied when the Update function returns without error
+type Txn interface {
+	Get(key []byte) ([]byte, error)
+	Set(key []byte, value []byte) error
+	Delete(key []byte) error
+	Iterate(begin []byte, prefix []byte, fn func(key []byte, value []byte) error) error
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func Test_MemoryDB(t *testing.T) {
+	testDB(t, NewMemoryDB())
+}
+
+func Test_BadgerDB(t *testing.T) {
+	path, err := ioutil.TempDir("", "badger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	bdb, err := NewBadgerDB(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDB(t, bdb)
+}
+
+func testDB(t *testing.T, kv DB) {
+	defer kv.Close()
+
+	if err := kv.Update(func(txn Txn) error {
+		for i := 0; i < 20; i++ {
+			if err := txn.Set([]byte("a"+strconv.Itoa(i)), []byte(strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+		if err := txn.Set([]byte("b0"), []byte{}); err != nil {
+			return err
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	errRollback := errors.New("rollback")
+	if err := kv.Update(func(txn Txn) error {
+		if err := txn.Set([]byte("a5"), []byte("updated")); err != nil {
+			return err
+		}
+		if err := txn.Delete([]byte("a6")); err != nil {
+			return err
+		}
+		return errRollback
+	}); err != errRollback {
+		t.Fatal("rollback error is not returned", err)
+	}
+
+	if err := kv.View(func(txn Txn) error {
+		if value, err := txn.Get([]byte("a5")); err != nil {
+			return err
+		} else if string(value) != "5" {
+			t.Error("rolled back value is stored", string(value))
+		}
+		if _, err := txn.Get([]byte("a6")); err != nil {
+			return err
+		}
+		if value, err := txn.Get([]byte("b0")); err != nil {
+			return err
+		} else if value == nil || len(value) != 0 {
+			t.Error("empty value is not returned", value)
+		}
+		if _, err := txn.Get([]byte("c0")); err != ErrNotExistKey {
+			t.Error("not exist key is returned", err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := kv.Update(func(txn Txn) error {
+		if err := txn.Delete([]byte("a10")); err != nil {
+			return err
+		}
+		if err := txn.Set([]byte("a100"), []byte("100")); err != nil {
+			return err
+		}
+		if err := txn.Set([]byte("a1"), []byte("updated")); err != nil {
+			return err
+		}
+		// updates of the transaction are merged to the iteration of it
+		keys := []string{}
+		if err := txn.Iterate([]byte("a1"), []byte("a1"), func(key []byte, value []byte) error {
+			keys = append(keys, string(key))
+			return nil
+		}); err != nil {
+			return err
+		}
+		expected := []string{"a1", "a100", "a11", "a12", "a13", "a14", "a15", "a16", "a17", "a18", "a19"}
+		if !equalKeys(keys, expected) {
+			t.Error("invalid iteration in the transaction", keys)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := kv.View(func(txn Txn) error {
+		keys := []string{}
+		if err := txn.Iterate([]byte("a15"), []byte("a"), func(key []byte, value []byte) error {
+			keys = append(keys, string(key))
+			if len(keys) >= 3 {
+				return ErrStopIteration
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		if !equalKeys(keys, []string{"a15", "a16", "a17"}) {
+			t.Error("invalid iteration from the begin key", keys)
+		}
+
+		keys = []string{}
+		if err := txn.Iterate([]byte("a1"), []byte("a1"), func(key []byte, value []byte) error {
+			keys = append(keys, string(key))
+			return nil
+		}); err != nil {
+			return err
+		}
+		if !equalKeys(keys, []string{"a1", "a100", "a11", "a12", "a13", "a14", "a15", "a16", "a17", "a18", "a19"}) {
+			t.Error("invalid iteration after the update", keys)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func equalKeys(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -4,5 +4,8 @@ import "errors"
 
 // db errors
 var (
-	ErrNotExistKey = errors.New("not exist key")
+	ErrNotExistKey   = errors.New("not exist key")
+	ErrStopIteration = errors.New("stop iteration")
+	ErrClosedDB      = errors.New("closed db")
+	ErrReadOnlyTxn   = errors.New("read only txn")
 )
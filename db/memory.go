@@ -0,0 +1,207 @@
+package db
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryDB is a DB that keeps all data in the memory
+// It is used for tests and ephemeral chains
+type MemoryDB struct {
+	sync.RWMutex
+	data    map[string][]byte
+	keys    []string
+	isClose bool
+}
+
+// NewMemoryDB returns a MemoryDB
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{
+		data: map[string][]byte{},
+	}
+}
+
+// View executes the read only transaction
+func (db *MemoryDB) View(fn func(txn Txn) error) error {
+	db.RLock()
+	defer db.RUnlock()
+	if db.isClose {
+		return ErrClosedDB
+	}
+
+	return fn(&memoryTxn{
+		db: db,
+	})
+}
+
+// Update executes the read write transaction
+func (db *MemoryDB) Update(fn func(txn Txn) error) error {
+	db.Lock()
+	defer db.Unlock()
+	if db.isClose {
+		return ErrClosedDB
+	}
+
+	txn := &memoryTxn{
+		db:         db,
+		isWritable: true,
+		setMap:     map[string][]byte{},
+		deletedMap: map[string]bool{},
+	}
+	if err := fn(txn); err != nil {
+		return err
+	}
+	for k := range txn.deletedMap {
+		if _, has := db.data[k]; has {
+			delete(db.data, k)
+			db.removeKey(k)
+		}
+	}
+	for k, v := range txn.setMap {
+		if _, has := db.data[k]; !has {
+			db.insertKey(k)
+		}
+		db.data[k] = v
+	}
+	return nil
+}
+
+// insertKey inserts the key to the sorted keys that are used by the iteration
+func (db *MemoryDB) insertKey(key string) {
+	idx := sort.SearchStrings(db.keys, key)
+	db.keys = append(db.keys, "")
+	copy(db.keys[idx+1:], db.keys[idx:])
+	db.keys[idx] = key
+}
+
+// removeKey removes the key from the sorted keys that are used by the iteration
+func (db *MemoryDB) removeKey(key string) {
+	idx := sort.SearchStrings(db.keys, key)
+	if idx < len(db.keys) && db.keys[idx] == key {
+		db.keys = append(db.keys[:idx], db.keys[idx+1:]...)
+	}
+}
+
+// Compact does nothing because deleted values are removed immediately
+func (db *MemoryDB) Compact() error {
+	return nil
+}
+
+// Close terminates the db
+func (db *MemoryDB) Close() error {
+	db.Lock()
+	defer db.Unlock()
+
+	db.isClose = true
+	db.data = nil
+	db.keys = nil
+	return nil
+}
+
+type memoryTxn struct {
+	db         *MemoryDB
+	isWritable bool
+	setMap     map[string][]byte
+	deletedMap map[string]bool
+}
+
+// Get returns the value of the key
+func (txn *memoryTxn) Get(key []byte) ([]byte, error) {
+	if txn.isWritable {
+		if v, has := txn.setMap[string(key)]; has {
+			return copyBytes(v), nil
+		}
+		if txn.deletedMap[string(key)] {
+			return nil, ErrNotExistKey
+		}
+	}
+	if v, has := txn.db.data[string(key)]; has {
+		return copyBytes(v), nil
+	}
+	return nil, ErrNotExistKey
+}
+
+// Set updates the value of the key
+func (txn *memoryTxn) Set(key []byte, value []byte) error {
+	if !txn.isWritable {
+		return ErrReadOnlyTxn
+	}
+	delete(txn.deletedMap, string(key))
+	txn.setMap[string(key)] = copyBytes(value)
+	return nil
+}
+
+// Delete deletes the key
+func (txn *memoryTxn) Delete(key []byte) error {
+	if !txn.isWritable {
+		return ErrReadOnlyTxn
+	}
+	delete(txn.setMap, string(key))
+	txn.deletedMap[string(key)] = true
+	return nil
+}
+
+// Iterate calls the function with keys that have the prefix from the begin key by the ascending order
+// The iteration is stopped without an error when the function returns ErrStopIteration
+// Stored keys are searched from the sorted keys and keys updated by the transaction are merged to them
+func (txn *memoryTxn) Iterate(begin []byte, prefix []byte, fn func(key []byte, value []byte) error) error {
+	from := string(begin)
+	if from < string(prefix) {
+		from = string(prefix)
+	}
+	pending := []string{}
+	if txn.isWritable {
+		for k := range txn.setMap {
+			if k >= from && strings.HasPrefix(k, string(prefix)) {
+				pending = append(pending, k)
+			}
+		}
+		sort.Strings(pending)
+	}
+
+	keys := txn.db.keys
+	i := sort.SearchStrings(keys, from)
+	j := 0
+	for {
+		var k string
+		hasStored := i < len(keys) && strings.HasPrefix(keys[i], string(prefix))
+		if hasStored && j < len(pending) {
+			if keys[i] < pending[j] {
+				k = keys[i]
+				i++
+			} else {
+				if keys[i] == pending[j] {
+					i++
+				}
+				k = pending[j]
+				j++
+			}
+		} else if hasStored {
+			k = keys[i]
+			i++
+		} else if j < len(pending) {
+			k = pending[j]
+			j++
+		} else {
+			return nil
+		}
+		if txn.isWritable && txn.deletedMap[k] {
+			continue
+		}
+		value, err := txn.Get([]byte(k))
+		if err != nil {
+			return err
+		}
+		if err := fn([]byte(k), value); err != nil {
+			if err == ErrStopIteration {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func copyBytes(bs []byte) []byte {
+	return append([]byte{}, bs...)
+}
@@ -75,6 +75,7 @@ func NewFormulator(Config *Config, kn *kernel.Kernel) (*Formulator, error) {
 	fr.mm.SetCreator(message_def.BlockReqMessageType, fr.messageCreator)
 	fr.mm.SetCreator(message_def.BlockObSignMessageType, fr.messageCreator)
 	fr.mm.SetCreator(message_def.TransactionMessageType, fr.messageCreator)
+	fr.mm.SetCreator(message_def.BundleMessageType, fr.messageCreator)
 	fr.mm.SetCreator(chain.DataMessageType, fr.messageCreator)
 	fr.mm.SetCreator(chain.StatusMessageType, fr.messageCreator)
 
@@ -432,6 +433,13 @@ func (fr *Formulator) handleMessage(p mesh.Peer, m message.Message, RetryCount i
 			return err
 		}
 		return nil
+	case *message_def.BundleMessage:
+		if _, err := fr.kn.AddBundle(msg.Txs, msg.Sigs); err != nil {
+			if err != kernel.ErrExistBundle && err != txpool.ErrExistTransaction {
+				return err
+			}
+		}
+		return nil
 	default:
 		return message.ErrUnhandledMessage
 	}
@@ -487,6 +495,14 @@ func (fr *Formulator) messageCreator(r io.Reader, t message.Type) (message.Messa
 			return nil, err
 		}
 		return p, nil
+	case message_def.BundleMessageType:
+		p := &message_def.BundleMessage{
+			Tran: fr.kn.Transactor(),
+		}
+		if _, err := p.ReadFrom(r); err != nil {
+			return nil, err
+		}
+		return p, nil
 	case chain.DataMessageType:
 		p := &chain.DataMessage{
 			Data: &chain.Data{
@@ -532,6 +548,15 @@ func (fr *Formulator) DoTransactionBroadcast(kn *kernel.Kernel, msg *message_def
 	fr.pm.BroadCast(msg)
 }
 
+// DoBundleBroadcast called when a bundle need to be broadcast
+func (fr *Formulator) DoBundleBroadcast(kn *kernel.Kernel, msg *message_def.BundleMessage) {
+	fr.pm.BroadCast(msg)
+}
+
+// OnContextDivergence called when the context hash of the block is not matched with the local execution
+func (fr *Formulator) OnContextDivergence(kn *kernel.Kernel, report *kernel.DivergenceReport) {
+}
+
 // DebugLog TEMP
 func (fr *Formulator) DebugLog(kn *kernel.Kernel, args ...interface{}) {
 }
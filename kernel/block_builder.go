@@ -0,0 +1,65 @@
+package kernel
+
+import (
+	"time"
+
+	"github.com/fletaio/core/txpool"
+)
+
+// BlockBuilder decides transactions of the block that is generated by the kernel
+type BlockBuilder interface {
+	// TimeBudget returns the maximum duration to select and execute transactions of the block
+	TimeBudget() time.Duration
+	// MaxBytes returns the maximum size of transactions of the block and zero means no limit
+	MaxBytes() int64
+	// Select pops items from the pool that is locked by the kernel and returns them to execute in the order
+	// The sum of sizes of items should not exceed RemainBytes and an empty result finishes the block
+	// Items that are failed to execute are dropped, so the builder should restore items that it doesn't return
+	Select(tp *txpool.TransactionPool, SeqCache txpool.SeqCache, MaxCount int, RemainBytes int64) []*txpool.PoolItem
+}
+
+// DefaultBlockBuilder selects items by the order of the fee and items that have the same fee by FIFO
+type DefaultBlockBuilder struct {
+	Budget time.Duration
+	Bytes  int64
+}
+
+// NewDefaultBlockBuilder returns a DefaultBlockBuilder
+func NewDefaultBlockBuilder() *DefaultBlockBuilder {
+	return &DefaultBlockBuilder{
+		Budget: 200 * time.Millisecond,
+	}
+}
+
+// TimeBudget returns the maximum duration to select and execute transactions of the block
+func (bb *DefaultBlockBuilder) TimeBudget() time.Duration {
+	return bb.Budget
+}
+
+// MaxBytes returns the maximum size of transactions of the block and zero means no limit
+func (bb *DefaultBlockBuilder) MaxBytes() int64 {
+	return bb.Bytes
+}
+
+// Select pops items from the pool by the order of the priority
+// Items that exceed the remained size are restored to the pool after the selection, so smaller items can be included instead of them
+func (bb *DefaultBlockBuilder) Select(tp *txpool.TransactionPool, SeqCache txpool.SeqCache, MaxCount int, RemainBytes int64) []*txpool.PoolItem {
+	items := []*txpool.PoolItem{}
+	skipped := []*txpool.PoolItem{}
+	for len(items) < MaxCount {
+		item := tp.UnsafePop(SeqCache)
+		if item == nil {
+			break
+		}
+		if item.Size > RemainBytes {
+			skipped = append(skipped, item)
+			continue
+		}
+		RemainBytes -= item.Size
+		items = append(items, item)
+	}
+	for _, item := range skipped {
+		tp.UnsafeRestore(item)
+	}
+	return items
+}
@@ -0,0 +1,386 @@
+package kernel
+
+import (
+	"bytes"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/core/block"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/core/message_def"
+	"github.com/fletaio/core/transaction"
+	"github.com/fletaio/core/txpool"
+)
+
+// maxBundleFailures is the number of failed executions that removes the bundle
+// The failed bundle is tried again after the number of blocks that is doubled by each failure
+const maxBundleFailures = 5
+
+// txBundle is a group of transactions that are included to the same block or not at all
+type txBundle struct {
+	Hash         hash.Hash256
+	Transactions []transaction.Transaction
+	Signatures   [][]common.Signature
+	PushedAt     int64
+	FailCount    int
+	RetryHeight  uint32
+}
+
+// bundleHash returns the hash of hashes of transactions of the bundle
+func bundleHash(txs []transaction.Transaction) hash.Hash256 {
+	var buffer bytes.Buffer
+	for _, tx := range txs {
+		TxHash := tx.Hash()
+		buffer.Write(TxHash[:])
+	}
+	return hash.Hash(buffer.Bytes())
+}
+
+// AddTransactions validates transactions in parallel and pushes them to the transaction pool in the order of the sequence
+// Transactions are pushed independently, so the error of each transaction is returned at its position
+func (kn *Kernel) AddTransactions(txs []transaction.Transaction, sigs [][]common.Signature) []error {
+	kn.closeLock.RLock()
+	defer kn.closeLock.RUnlock()
+
+	errs := make([]error, len(txs))
+	if kn.isClose {
+		for i := range errs {
+			errs[i] = ErrKernelClosed
+		}
+		return errs
+	}
+	if len(txs) != len(sigs) {
+		for i := range errs {
+			errs[i] = ErrInvalidSignatureCount
+		}
+		return errs
+	}
+
+	signers, signerErrs := recoverSignersParallel(txs, sigs)
+
+	// transactions of the same address are pushed in the order of the sequence at positions of them and others keep the given order
+	idxs := make([]int, len(txs))
+	posMap := map[common.Address][]int{}
+	for i, tx := range txs {
+		idxs[i] = i
+		if atx, is := tx.(txpool.AccountTransaction); is {
+			posMap[atx.From()] = append(posMap[atx.From()], i)
+		}
+	}
+	for _, pos := range posMap {
+		sorted := make([]int, len(pos))
+		copy(sorted, pos)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return txs[sorted[i]].(txpool.AccountTransaction).Seq() < txs[sorted[j]].(txpool.AccountTransaction).Seq()
+		})
+		for i, p := range pos {
+			idxs[p] = sorted[i]
+		}
+	}
+
+	now := time.Now().UnixNano()
+	for _, i := range idxs {
+		if signerErrs[i] != nil {
+			errs[i] = signerErrs[i]
+			continue
+		}
+		errs[i] = kn.addTransaction(&txpool.PoolItem{
+			Transaction: txs[i],
+			TxHash:      txs[i].Hash(),
+			Signatures:  sigs[i],
+			PushedAt:    now,
+		}, signers[i])
+	}
+	return errs
+}
+
+// AddBundle validates transactions as the bundle and keeps it to include them to the same block or not at all
+// Transactions of the bundle are executed in the given order, so later transactions can use results of previous ones
+func (kn *Kernel) AddBundle(txs []transaction.Transaction, sigs [][]common.Signature) (hash.Hash256, error) {
+	kn.closeLock.RLock()
+	defer kn.closeLock.RUnlock()
+	if kn.isClose {
+		return hash.Hash256{}, ErrKernelClosed
+	}
+
+	BundleHash, err := kn.addBundle(txs, sigs, time.Now().UnixNano())
+	if err != nil {
+		return hash.Hash256{}, err
+	}
+	msg := &message_def.BundleMessage{
+		Txs:  txs,
+		Sigs: sigs,
+		Tran: kn.Transactor(),
+	}
+	for _, eh := range kn.eventHandlers {
+		eh.DoBundleBroadcast(kn, msg)
+	}
+	return BundleHash, nil
+}
+
+// addBundle validates transactions as the bundle and keeps it with the pushed time
+// It is used to add the new bundle and to restore the bundle that is added before
+func (kn *Kernel) addBundle(txs []transaction.Transaction, sigs [][]common.Signature, PushedAt int64) (hash.Hash256, error) {
+	if len(txs) == 0 || len(txs) > kn.Config.MaxTransactionsPerBlock {
+		return hash.Hash256{}, ErrInvalidBundle
+	}
+	if len(txs) != len(sigs) {
+		return hash.Hash256{}, ErrInvalidSignatureCount
+	}
+
+	TxHashes := make([]hash.Hash256, 0, len(txs))
+	for _, tx := range txs {
+		TxHashes = append(TxHashes, tx.Hash())
+	}
+	BundleHash := bundleHash(txs)
+
+	kn.Lock()
+	_, has := kn.bundleMap[BundleHash]
+	kn.Unlock()
+	if has {
+		return hash.Hash256{}, ErrExistBundle
+	}
+	if kn.Config.MaxTransactionBytes > 0 {
+		for i, tx := range txs {
+			if Size, err := txpool.TransactionSize(tx, sigs[i]); err != nil {
+				return hash.Hash256{}, err
+			} else if Size > kn.Config.MaxTransactionBytes {
+				return hash.Hash256{}, ErrExceedTransactionSize
+			}
+		}
+	}
+	checkMap := map[hash.Hash256]bool{}
+	for _, TxHash := range TxHashes {
+		if checkMap[TxHash] || kn.txPool.IsExist(TxHash) {
+			return hash.Hash256{}, txpool.ErrExistTransaction
+		}
+		checkMap[TxHash] = true
+	}
+
+	signers, signerErrs := recoverSignersParallel(txs, sigs)
+	for _, err := range signerErrs {
+		if err != nil {
+			return hash.Hash256{}, err
+		}
+	}
+
+	ctx := data.NewContext(kn.store)
+	now := time.Now().UnixNano()
+	for i, tx := range txs {
+		if transaction.IsExpired(tx, ctx.TargetHeight(), uint64(now)) {
+			return hash.Hash256{}, transaction.ErrExpiredTransaction
+		}
+		if err := ctx.Transactor().Validate(ctx, tx, signers[i]); err != nil {
+			return hash.Hash256{}, err
+		}
+		if _, err := ctx.Transactor().Execute(ctx, tx, &common.Coordinate{Height: ctx.TargetHeight(), Index: uint16(i)}); err != nil {
+			return hash.Hash256{}, err
+		}
+	}
+
+	bd := &txBundle{
+		Hash:         BundleHash,
+		Transactions: txs,
+		Signatures:   sigs,
+		PushedAt:     PushedAt,
+	}
+	kn.Lock()
+	if _, has := kn.bundleMap[BundleHash]; has {
+		kn.Unlock()
+		return hash.Hash256{}, ErrExistBundle
+	}
+	for _, TxHash := range TxHashes {
+		if _, has := kn.bundleTxMap[TxHash]; has {
+			kn.Unlock()
+			return hash.Hash256{}, txpool.ErrExistTransaction
+		}
+	}
+	kn.bundleMap[BundleHash] = bd
+	for i, TxHash := range TxHashes {
+		kn.bundleTxMap[TxHash] = BundleHash
+		kn.txSignersMap[TxHash] = signers[i]
+	}
+	kn.Unlock()
+
+	if kn.Config.PersistTxPool {
+		if err := kn.store.storePoolBundle(bd); err != nil {
+			kn.DebugLog("Kernel", "Store Pool Bundle Failed :", BundleHash.String(), err)
+		}
+	}
+	return BundleHash, nil
+}
+
+// appendBundles executes bundles on the context and appends transactions of executed bundles to the block
+// The failed bundle is reverted and tried again after the back-off until it is expired or fails maxBundleFailures times
+// It returns hashes of transactions of the block and the size of them
+func (kn *Kernel) appendBundles(ctx *data.Context, b *block.Block, TxHashes []hash.Hash256, MaxBytes int64) ([]hash.Hash256, int64, error) {
+	kn.Lock()
+	bundles := make([]*txBundle, 0, len(kn.bundleMap))
+	for _, bd := range kn.bundleMap {
+		bundles = append(bundles, bd)
+	}
+	kn.Unlock()
+	sort.Slice(bundles, func(i, j int) bool {
+		return bundles[i].PushedAt < bundles[j].PushedAt
+	})
+
+	var Bytes int64
+	failed := []*txBundle{}
+BundleLoop:
+	for _, bd := range bundles {
+		if b.Header.Height() < bd.RetryHeight {
+			continue
+		}
+		if len(TxHashes)+len(bd.Transactions) > kn.Config.MaxTransactionsPerBlock+1 {
+			continue
+		}
+		var Size int64
+		for i, tx := range bd.Transactions {
+			// the bundle that is not valid yet is not failed and the expired one is removed when the block is connected
+			if err := transaction.CheckWindow(tx, b.Header.Height(), b.Header.Timestamp()); err != nil {
+				continue BundleLoop
+			}
+			if v, err := txpool.TransactionSize(tx, bd.Signatures[i]); err != nil {
+				continue BundleLoop
+			} else {
+				Size += v
+			}
+		}
+		if Bytes+Size > MaxBytes {
+			continue
+		}
+		sn := ctx.Snapshot()
+		if _, err := ctx.Transactor().ExecuteTransactions(ctx, bd.Transactions, ctx.TargetHeight(), uint16(len(b.Body.Transactions)), false); err != nil {
+			ctx.Revert(sn)
+			failed = append(failed, bd)
+			continue
+		}
+		ctx.Commit(sn)
+		for i, tx := range bd.Transactions {
+			b.Body.Transactions = append(b.Body.Transactions, tx)
+			b.Body.TransactionSignatures = append(b.Body.TransactionSignatures, bd.Signatures[i])
+			TxHashes = append(TxHashes, tx.Hash())
+		}
+		Bytes += Size
+	}
+	if len(failed) > 0 {
+		kn.failBundles(failed, b.Header.Height())
+	}
+	if ctx.StackSize() > 1 {
+		return nil, 0, ErrDirtyContext
+	}
+	return TxHashes, Bytes, nil
+}
+
+// failBundles delays next tries of failed bundles by the back-off and removes bundles that fail too many times
+func (kn *Kernel) failBundles(failed []*txBundle, height uint32) {
+	removed := []hash.Hash256{}
+	kn.Lock()
+	for _, bd := range failed {
+		if _, has := kn.bundleMap[bd.Hash]; !has {
+			continue
+		}
+		bd.FailCount++
+		if bd.FailCount >= maxBundleFailures {
+			kn.deleteBundle(bd)
+			removed = append(removed, bd.Hash)
+		} else {
+			bd.RetryHeight = height + 1<<uint(bd.FailCount)
+		}
+	}
+	kn.Unlock()
+
+	if kn.Config.PersistTxPool && len(removed) > 0 {
+		if err := kn.store.deletePoolBundles(removed); err != nil {
+			kn.DebugLog("Kernel", "Delete Pool Bundles Failed :", err)
+		}
+	}
+}
+
+// deleteBundle removes the bundle and transactions of it without mutex locking
+func (kn *Kernel) deleteBundle(bd *txBundle) {
+	for _, tx := range bd.Transactions {
+		TxHash := tx.Hash()
+		delete(kn.bundleTxMap, TxHash)
+		delete(kn.txSignersMap, TxHash)
+	}
+	delete(kn.bundleMap, bd.Hash)
+}
+
+// removeBundles removes bundles that have a transaction of the block or are expired
+// A bundle that is partially included cannot be included atomically, so it is removed together
+func (kn *Kernel) removeBundles(b *block.Block) {
+	kn.Lock()
+	removeMap := map[hash.Hash256]bool{}
+	for _, tx := range b.Body.Transactions {
+		if BundleHash, has := kn.bundleTxMap[tx.Hash()]; has {
+			removeMap[BundleHash] = true
+		}
+	}
+	now := time.Now().UnixNano()
+	for BundleHash, bd := range kn.bundleMap {
+		if now-bd.PushedAt > int64(txPoolItemLifetime) {
+			removeMap[BundleHash] = true
+			continue
+		}
+		for _, tx := range bd.Transactions {
+			if transaction.IsExpired(tx, b.Header.Height()+1, b.Header.Timestamp()) {
+				removeMap[BundleHash] = true
+				break
+			}
+		}
+	}
+	removed := make([]hash.Hash256, 0, len(removeMap))
+	for BundleHash := range removeMap {
+		kn.deleteBundle(kn.bundleMap[BundleHash])
+		removed = append(removed, BundleHash)
+	}
+	kn.Unlock()
+
+	if kn.Config.PersistTxPool && len(removed) > 0 {
+		if err := kn.store.deletePoolBundles(removed); err != nil {
+			kn.DebugLog("Kernel", "Delete Pool Bundles Failed :", err)
+		}
+	}
+}
+
+// recoverSigners returns public hashes of signers of the transaction hash
+func recoverSigners(TxHash hash.Hash256, sigs []common.Signature) ([]common.PublicHash, error) {
+	signers := make([]common.PublicHash, 0, len(sigs))
+	for _, sig := range sigs {
+		pubkey, err := common.RecoverPubkey(TxHash, sig)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, common.NewPublicHash(pubkey))
+	}
+	return signers, nil
+}
+
+// recoverSignersParallel returns signers of transactions and errors of them at their positions
+func recoverSignersParallel(txs []transaction.Transaction, sigs [][]common.Signature) ([][]common.PublicHash, []error) {
+	signers := make([][]common.PublicHash, len(txs))
+	errs := make([]error, len(txs))
+	idxCh := make(chan int, len(txs))
+	for i := range txs {
+		idxCh <- i
+	}
+	close(idxCh)
+
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range idxCh {
+				signers[idx], errs[idx] = recoverSigners(txs[idx].Hash(), sigs[idx])
+			}
+		}()
+	}
+	wg.Wait()
+	return signers, errs
+}
@@ -10,4 +10,15 @@ type Config struct {
 	ObserverKeyMap          map[common.PublicHash]bool
 	MaxBlocksPerFormulator  uint32
 	MaxTransactionsPerBlock int
+	IndexTransactions       bool
+	KeepBlocks              uint32
+	DebugDir                string
+	PersistTxPool           bool
+	MaxPendingPerAddress    int
+	MaxTxPoolSize           int
+	MaxTxPoolBytes          int64
+	MaxFutureSeqGap         uint64
+	BlockBuilder            BlockBuilder
+	MaxBlockBytes           int64
+	MaxTransactionBytes     int64
 }
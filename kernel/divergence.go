@@ -0,0 +1,131 @@
+package kernel
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/core/block"
+	"github.com/fletaio/core/data"
+)
+
+// DivergenceReport is the diagnostic report of the block that has a different context hash with the local execution
+// Section hashes of reports from nodes can be compared to find the diverged section of the context data
+type DivergenceReport struct {
+	Height       uint32
+	BlockHash    hash.Hash256
+	Formulator   common.Address
+	LastHash     hash.Hash256
+	ExpectedHash hash.Hash256
+	ContextHash  hash.Hash256
+	Sections     []*data.SectionHash
+	Dump         string
+}
+
+// NewDivergenceReport returns a DivergenceReport of the block and the context that is executed by the block
+func NewDivergenceReport(b *block.Block, ctx *data.Context) *DivergenceReport {
+	return &DivergenceReport{
+		Height:       b.Header.Height(),
+		BlockHash:    b.Header.Hash(),
+		Formulator:   b.Header.Formulator,
+		LastHash:     ctx.LastHash(),
+		ExpectedHash: b.Header.ContextHash,
+		ContextHash:  ctx.Hash(),
+		Sections:     ctx.SectionHashes(),
+		Dump:         ctx.Dump(),
+	}
+}
+
+// Save writes the report as json and the dump of the context data as text to the directory
+// Names of files are the height and the block hash, so reports of the same block from nodes have the same name
+func (r *DivergenceReport) Save(dir string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	name := "divergence_" + strconv.FormatUint(uint64(r.Height), 10) + "_" + r.BlockHash.String()
+	bs, err := r.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".json"), bs, 0644); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".dump"), []byte(r.Dump), 0644); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalJSON is a marshaler function
+func (r *DivergenceReport) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(`{`)
+	buffer.WriteString(`"height":`)
+	if bs, err := json.Marshal(r.Height); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"block_hash":`)
+	if bs, err := r.BlockHash.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"formulator":`)
+	if bs, err := r.Formulator.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"last_hash":`)
+	if bs, err := r.LastHash.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"expected_hash":`)
+	if bs, err := r.ExpectedHash.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"context_hash":`)
+	if bs, err := r.ContextHash.MarshalJSON(); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"sections":`)
+	buffer.WriteString(`{`)
+	for i, s := range r.Sections {
+		if i > 0 {
+			buffer.WriteString(`,`)
+		}
+		if bs, err := json.Marshal(s.Name); err != nil {
+			return nil, err
+		} else {
+			buffer.Write(bs)
+		}
+		buffer.WriteString(`:`)
+		if bs, err := s.Hash.MarshalJSON(); err != nil {
+			return nil, err
+		} else {
+			buffer.Write(bs)
+		}
+	}
+	buffer.WriteString(`}`)
+	buffer.WriteString(`}`)
+	return buffer.Bytes(), nil
+}
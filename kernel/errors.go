@@ -25,4 +25,26 @@ var (
 	ErrPastSeq                   = errors.New("past seq")
 	ErrTooFarSeq                 = errors.New("too far seq")
 	ErrTxQueueOverflowed         = errors.New("tx queue overflowed")
+	ErrInvalidRewindHeight       = errors.New("invalid rewind height")
+	ErrNotExistUndoData          = errors.New("not exist undo data")
+	ErrInvalidStateKey           = errors.New("invalid state key")
+	ErrInvalidStateRootHash      = errors.New("invalid state root hash")
+	ErrInvalidTransactionIndex   = errors.New("invalid transaction index")
+	ErrDisabledIndex             = errors.New("disabled index")
+	ErrInvalidIndexLimit         = errors.New("invalid index limit")
+	ErrIndexNotFromGenesis       = errors.New("index not from genesis")
+	ErrIndexNotDisabled          = errors.New("index not disabled")
+	ErrPrunedData                = errors.New("pruned data")
+	ErrIncompatibleStoreVersion  = errors.New("incompatible store version")
+	ErrInvalidStoredAccount      = errors.New("invalid stored account")
+	ErrInvalidSnapshotHeight     = errors.New("invalid snapshot height")
+	ErrNotEmptySnapshotPath      = errors.New("not empty snapshot path")
+	ErrInvalidSnapshot           = errors.New("invalid snapshot")
+	ErrInvalidSnapshotVersion    = errors.New("invalid snapshot version")
+	ErrInvalidSnapshotHash       = errors.New("invalid snapshot hash")
+	ErrInvalidSnapshotChecksum   = errors.New("invalid snapshot checksum")
+	ErrInvalidBundle             = errors.New("invalid bundle")
+	ErrExistBundle               = errors.New("exist bundle")
+	ErrExceedBlockSize           = errors.New("exceed block size")
+	ErrExceedTransactionSize     = errors.New("exceed transaction size")
 )
@@ -20,6 +20,10 @@ type EventHandler interface {
 	AfterPushTransaction(kn *Kernel, tx transaction.Transaction, sigs []common.Signature)
 	// DoTransactionBroadcast called when a transaction need to be broadcast
 	DoTransactionBroadcast(kn *Kernel, msg *message_def.TransactionMessage)
+	// DoBundleBroadcast called when a bundle need to be broadcast
+	DoBundleBroadcast(kn *Kernel, msg *message_def.BundleMessage)
+	// OnContextDivergence called when the context hash of the block is not matched with the local execution
+	OnContextDivergence(kn *Kernel, report *DivergenceReport)
 	// DebugLog TEMP
 	DebugLog(kn *Kernel, args ...interface{})
 }
@@ -0,0 +1,233 @@
+package kernel
+
+import (
+	"bytes"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/core/block"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/core/db"
+	"github.com/fletaio/core/transaction"
+	"github.com/fletaio/core/txpool"
+)
+
+// RecipientTransaction is an interface that defines the recipients of the transaction for the transaction index
+// Accounts created by the transaction are indexed without it because their addresses have the coordinate of the transaction
+type RecipientTransaction interface {
+	Recipients() []common.Address
+}
+
+// setIndexing enables the transaction index only when it is built from the genesis
+// The index of the store that has stored blocks without it cannot be enabled because the missing part is not recovered
+// and the index that is built cannot be disabled because blocks stored after it are not indexed, so the store should be synced again to change it
+func (st *Store) setIndexing(enabled bool) error {
+	if err := st.db.Update(func(txn db.Txn) error {
+		if _, err := txn.Get([]byte("height")); err != nil {
+			if err != db.ErrNotExistKey {
+				return err
+			}
+			if enabled {
+				return txn.Set([]byte("txindex"), []byte{1})
+			}
+			return nil
+		}
+		if _, err := txn.Get([]byte("txindex")); err != nil {
+			if err != db.ErrNotExistKey {
+				return err
+			}
+			if enabled {
+				return ErrIndexNotFromGenesis
+			}
+		} else if !enabled {
+			return ErrIndexNotDisabled
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	st.isIndexing = enabled
+	return nil
+}
+
+// indexTransactions stores the coordinates of transactions by the hash, the related addresses and the related utxo owners
+// It should be called after the context data is applied to find owners of the spent utxos from the undo journal
+func indexTransactions(ut *undoTxn, height uint32, body *block.Body, ctd *data.ContextData) error {
+	spentMap := map[uint64]*transaction.TxOut{}
+	for _, e := range ut.entries {
+		if !e.IsExist || len(e.Key) != 10 || !bytes.HasPrefix(e.Key, tagUTXO) {
+			continue
+		}
+		// the utxo that is updated but not deleted is not spent
+		if !ctd.DeletedUTXOMap[fromUTXOKey(e.Key)] {
+			continue
+		}
+		out := transaction.NewTxOut()
+		if _, err := out.ReadFrom(bytes.NewReader(e.Value)); err != nil {
+			return err
+		}
+		spentMap[fromUTXOKey(e.Key)] = out
+	}
+	ownerMap := map[uint16]map[common.PublicHash]bool{}
+	for id, out := range ctd.CreatedUTXOMap {
+		h, idx, _ := transaction.UnmarshalID(id)
+		if h != height {
+			continue
+		}
+		pubhashMap, has := ownerMap[idx]
+		if !has {
+			pubhashMap = map[common.PublicHash]bool{}
+			ownerMap[idx] = pubhashMap
+		}
+		pubhashMap[out.PublicHash] = true
+	}
+	createdMap := map[uint16][]common.Address{}
+	for addr := range ctd.CreatedAccountMap {
+		coord := addr.Coordinate()
+		if coord.Height != height {
+			continue
+		}
+		createdMap[coord.Index] = append(createdMap[coord.Index], addr)
+	}
+
+	for i, tx := range body.Transactions {
+		coord := common.NewCoordinate(height, uint16(i))
+		var buffer bytes.Buffer
+		if _, err := coord.WriteTo(&buffer); err != nil {
+			return err
+		}
+		if err := ut.Set(toTxHashKey(tx.Hash()), buffer.Bytes()); err != nil {
+			return err
+		}
+
+		addrMap := map[common.Address]bool{}
+		if atx, is := tx.(txpool.AccountTransaction); is {
+			addrMap[atx.From()] = true
+		}
+		if rtx, is := tx.(RecipientTransaction); is {
+			for _, addr := range rtx.Recipients() {
+				addrMap[addr] = true
+			}
+		}
+		for _, addr := range createdMap[uint16(i)] {
+			addrMap[addr] = true
+		}
+		for addr := range addrMap {
+			if err := ut.Set(toAddressTxKey(addr, coord), []byte{}); err != nil {
+				return err
+			}
+		}
+
+		pubhashMap := map[common.PublicHash]bool{}
+		for pubhash := range ownerMap[uint16(i)] {
+			pubhashMap[pubhash] = true
+		}
+		if utx, is := tx.(txpool.UTXOTransaction); is {
+			for _, id := range utx.VinIDs() {
+				if out, has := spentMap[id]; has {
+					pubhashMap[out.PublicHash] = true
+				}
+			}
+		}
+		for pubhash := range pubhashMap {
+			if err := ut.Set(toPublicHashTxKey(pubhash, coord), []byte{}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// TransactionCoordinate returns the coordinate of the transaction by the hash
+func (st *Store) TransactionCoordinate(TxHash hash.Hash256) (*common.Coordinate, error) {
+	st.closeLock.RLock()
+	defer st.closeLock.RUnlock()
+	if st.isClose {
+		return nil, ErrStoreClosed
+	}
+
+	if !st.isIndexing {
+		return nil, ErrDisabledIndex
+	}
+
+	coord := &common.Coordinate{}
+	if err := st.db.View(func(txn db.Txn) error {
+		value, err := txn.Get(toTxHashKey(TxHash))
+		if err != nil {
+			return err
+		}
+		if _, err := coord.ReadFrom(bytes.NewReader(value)); err != nil {
+			return err
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return coord, nil
+}
+
+// TransactionByHash returns the transaction and its coordinate by the hash
+func (st *Store) TransactionByHash(TxHash hash.Hash256) (transaction.Transaction, *common.Coordinate, error) {
+	coord, err := st.TransactionCoordinate(TxHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	cd, err := st.Data(coord.Height)
+	if err != nil {
+		return nil, nil, err
+	}
+	body := cd.Body.(*block.Body)
+	if int(coord.Index) >= len(body.Transactions) {
+		return nil, nil, db.ErrNotExistKey
+	}
+	return body.Transactions[coord.Index], coord, nil
+}
+
+// TransactionsByAddress returns coordinates of transactions related to the address after the cursor
+// The nil cursor returns from the first transaction and the last coordinate of the result is the cursor of the next page
+func (st *Store) TransactionsByAddress(addr common.Address, cursor *common.Coordinate, limit int) ([]*common.Coordinate, error) {
+	return st.indexedCoordinates(toAddressTxPrefix(addr), cursor, limit)
+}
+
+// TransactionsByPublicHash returns coordinates of transactions that create or spend utxos of the public hash after the cursor
+// The nil cursor returns from the first transaction and the last coordinate of the result is the cursor of the next page
+func (st *Store) TransactionsByPublicHash(pubhash common.PublicHash, cursor *common.Coordinate, limit int) ([]*common.Coordinate, error) {
+	return st.indexedCoordinates(toPublicHashTxPrefix(pubhash), cursor, limit)
+}
+
+func (st *Store) indexedCoordinates(prefix []byte, cursor *common.Coordinate, limit int) ([]*common.Coordinate, error) {
+	st.closeLock.RLock()
+	defer st.closeLock.RUnlock()
+	if st.isClose {
+		return nil, ErrStoreClosed
+	}
+
+	if !st.isIndexing {
+		return nil, ErrDisabledIndex
+	}
+	if limit <= 0 {
+		return nil, ErrInvalidIndexLimit
+	}
+
+	list := []*common.Coordinate{}
+	if err := st.db.View(func(txn db.Txn) error {
+		begin := prefix
+		if cursor != nil {
+			begin = toIndexTxKey(prefix, cursor)
+		}
+		return txn.Iterate(begin, prefix, func(key []byte, value []byte) error {
+			coord := fromIndexTxKey(key)
+			if cursor != nil && coord.Equal(cursor) {
+				return nil
+			}
+			list = append(list, coord)
+			if len(list) >= limit {
+				return db.ErrStopIteration
+			}
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
@@ -3,6 +3,7 @@ package kernel
 import (
 	"bytes"
 	"log"
+	"math"
 	"runtime"
 	"sort"
 	"sync"
@@ -20,6 +21,7 @@ import (
 	"github.com/fletaio/core/data"
 	"github.com/fletaio/core/db"
 	"github.com/fletaio/core/level"
+	"github.com/fletaio/core/statetree"
 	"github.com/fletaio/core/transaction"
 	"github.com/fletaio/core/txpool"
 	"github.com/fletaio/framework/chain"
@@ -37,6 +39,8 @@ type Kernel struct {
 	txQueue            *queue.ExpireQueue
 	txWorkingMap       map[hash.Hash256]bool
 	txSignersMap       map[hash.Hash256][]common.PublicHash
+	bundleMap          map[hash.Hash256]*txBundle
+	bundleTxMap        map[hash.Hash256]hash.Hash256
 	genesisContextData *data.ContextData
 	rd                 reward.Rewarder
 	eventHandlers      []EventHandler
@@ -62,11 +66,24 @@ func NewKernel(Config *Config, st *Store, rd reward.Rewarder, genesisContextData
 		txQueue:            queue.NewExpireQueue(),
 		txWorkingMap:       map[hash.Hash256]bool{},
 		txSignersMap:       map[hash.Hash256][]common.PublicHash{},
+		bundleMap:          map[hash.Hash256]*txBundle{},
+		bundleTxMap:        map[hash.Hash256]hash.Hash256{},
 		eventHandlers:      []EventHandler{},
 	}
+	if err := st.setIndexing(Config.IndexTransactions); err != nil {
+		return nil, err
+	}
+	st.keepBlocks = Config.KeepBlocks
+	st.stateRootLag = Config.MaxBlocksPerFormulator
+	kn.txPool.SetFeeCalculator(st.Transactor().Fee)
+	kn.txPool.SetLimits(txpool.Limits{
+		MaxPendingPerAddress: Config.MaxPendingPerAddress,
+		MaxSize:              Config.MaxTxPoolSize,
+		MaxBytes:             Config.MaxTxPoolBytes,
+	})
 	kn.txQueue.AddGroup(60 * time.Second)
 	kn.txQueue.AddGroup(600 * time.Second)
-	kn.txQueue.AddGroup(3600 * time.Second)
+	kn.txQueue.AddGroup(txPoolItemLifetime)
 	kn.txQueue.AddHandler(kn)
 
 	if bs := kn.store.CustomData("chaincoord"); bs != nil {
@@ -133,24 +150,74 @@ func NewKernel(Config *Config, st *Store, rd reward.Rewarder, genesisContextData
 		if !GenesisHash.Equal(h) {
 			return nil, chain.ErrInvalidGenesisHash
 		}
-		if SaveData := kn.store.CustomData("consensus"); SaveData == nil {
-			return nil, ErrNotExistConsensusSaveData
-		} else if err := kn.cs.LoadFromSaveData(SaveData); err != nil {
+		if err := kn.loadSaveData(); err != nil {
 			return nil, err
 		}
-		if SaveData := kn.store.CustomData("reward"); SaveData == nil {
-			return nil, ErrNotExistRewardSaveData
-		} else if err := kn.rd.LoadFromSaveData(SaveData); err != nil {
+	}
+	kn.genesisContextData = nil // to reduce memory usagse
+
+	if Config.PersistTxPool {
+		if err := kn.restoreTxPool(); err != nil {
 			return nil, err
 		}
 	}
-	kn.genesisContextData = nil // to reduce memory usagse
 
 	log.Println("Kernel", "Loaded with height of", kn.Provider().Height(), kn.Provider().LastHash())
 
 	return kn, nil
 }
 
+// restoreTxPool pushes items and bundles in the journal of the transaction pool again after validating them with the current state
+// Items and bundles that are expired or invalid by the current state are removed from the journal
+func (kn *Kernel) restoreTxPool() error {
+	items, err := kn.store.PoolItems()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UnixNano()
+	removed := []hash.Hash256{}
+	for _, item := range items {
+		if now-item.PushedAt > int64(txPoolItemLifetime) {
+			removed = append(removed, item.TxHash)
+			continue
+		}
+		if err := kn.addTransaction(item, nil); err != nil {
+			removed = append(removed, item.TxHash)
+		}
+	}
+	if len(removed) > 0 {
+		if err := kn.store.DeletePoolItems(removed); err != nil {
+			return err
+		}
+	}
+
+	bundles, err := kn.store.poolBundles()
+	if err != nil {
+		return err
+	}
+	return kn.restoreBundles(bundles, now)
+}
+
+// restoreBundles adds bundles again after validating them with the current state and removes invalid ones from the journal
+func (kn *Kernel) restoreBundles(bundles []*txBundle, now int64) error {
+	removed := []hash.Hash256{}
+	for _, bd := range bundles {
+		if now-bd.PushedAt > int64(txPoolItemLifetime) {
+			removed = append(removed, bundleHash(bd.Transactions))
+			continue
+		}
+		if _, err := kn.addBundle(bd.Transactions, bd.Signatures, bd.PushedAt); err != nil {
+			removed = append(removed, bundleHash(bd.Transactions))
+		}
+	}
+	if kn.Config.PersistTxPool && len(removed) > 0 {
+		if err := kn.store.deletePoolBundles(removed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Close terminates and cleans the kernel
 func (kn *Kernel) Close() {
 	kn.closeLock.Lock()
@@ -214,6 +281,28 @@ func (kn *Kernel) Block(height uint32) (*block.Block, error) {
 	return b, nil
 }
 
+// TransactionProof returns the header of the block and the level proof of the transaction in it
+// The leaf of the proof is the hash of the transaction and its level index is the transaction index + 1 because the first leaf is the prev hash
+func (kn *Kernel) TransactionProof(height uint32, index uint16) (*block.Header, [][]hash.Hash256, error) {
+	b, err := kn.Block(height)
+	if err != nil {
+		return nil, nil, err
+	}
+	if int(index) >= len(b.Body.Transactions) {
+		return nil, nil, ErrInvalidTransactionIndex
+	}
+	TxHashes := make([]hash.Hash256, 0, len(b.Body.Transactions)+1)
+	TxHashes = append(TxHashes, b.Header.PrevHash())
+	for _, tx := range b.Body.Transactions {
+		TxHashes = append(TxHashes, tx.Hash())
+	}
+	proof, err := level.BuildProof(TxHashes, int(index)+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b.Header, proof, nil
+}
+
 // CandidateCount returns a count of the rank table
 func (kn *Kernel) CandidateCount() int {
 	return kn.cs.CandidateCount()
@@ -434,12 +523,34 @@ func (kn *Kernel) Process(cd *chain.Data, UserData interface{}) error {
 	for _, eh := range kn.eventHandlers {
 		eh.AfterProcessBlock(kn, b, s, ctx)
 	}
+	TxHashes := make([]hash.Hash256, 0, len(b.Body.Transactions))
 	for _, tx := range b.Body.Transactions {
 		h := tx.Hash()
-		kn.txPool.Remove(h, tx)
+		// removed items include transactions that spend the same UTXOs with the confirmed transaction
+		for _, item := range kn.txPool.Remove(h, tx) {
+			if !item.TxHash.Equal(h) {
+				kn.txQueue.Remove(string(item.TxHash[:]))
+				delete(kn.txWorkingMap, item.TxHash)
+				delete(kn.txSignersMap, item.TxHash)
+				TxHashes = append(TxHashes, item.TxHash)
+			}
+		}
 		kn.txQueue.Remove(string(h[:]))
 		delete(kn.txWorkingMap, h)
 		delete(kn.txSignersMap, h)
+		TxHashes = append(TxHashes, h)
+	}
+	kn.removeBundles(b)
+	for _, item := range kn.txPool.RemoveExpired(b.Header.Height()+1, b.Header.Timestamp()) {
+		kn.txQueue.Remove(string(item.TxHash[:]))
+		delete(kn.txWorkingMap, item.TxHash)
+		delete(kn.txSignersMap, item.TxHash)
+		TxHashes = append(TxHashes, item.TxHash)
+	}
+	if kn.Config.PersistTxPool && len(TxHashes) > 0 {
+		if err := kn.store.DeletePoolItems(TxHashes); err != nil {
+			kn.DebugLog("Kernel", "Delete Pool Items Failed :", err)
+		}
 	}
 	kn.DebugLog("Kernel", "Block Connected :", kn.store.Height(), HeaderHash.String(), b.Header.Formulator.String(), len(b.Body.Transactions))
 	log.Println("Block Connected :", kn.store.Height(), HeaderHash.String(), b.Header.Formulator.String(), len(b.Body.Transactions))
@@ -454,21 +565,50 @@ func (kn *Kernel) AddTransaction(tx transaction.Transaction, sigs []common.Signa
 		return ErrKernelClosed
 	}
 
+	return kn.addTransaction(&txpool.PoolItem{
+		Transaction: tx,
+		TxHash:      tx.Hash(),
+		Signatures:  sigs,
+		PushedAt:    time.Now().UnixNano(),
+	}, nil)
+}
+
+// addTransaction validates the item and pushes it to the transaction pool
+// If signers is nil, signers are recovered from signatures of the item
+func (kn *Kernel) addTransaction(item *txpool.PoolItem, signers []common.PublicHash) error {
 	if kn.txQueue.Size() > 65535 {
 		return ErrTxQueueOverflowed
 	}
 
 	loader := kn.store
-	TxHash := tx.Hash()
+	tx := item.Transaction
+	sigs := item.Signatures
+	TxHash := item.TxHash
 	kn.Lock()
 	_, has := kn.txWorkingMap[TxHash]
 	if !has {
 		kn.txWorkingMap[TxHash] = true
 	}
+	_, inBundle := kn.bundleTxMap[TxHash]
 	kn.Unlock()
 	if has {
 		return ErrProcessingTransaction
 	}
+	defer func() {
+		kn.Lock()
+		delete(kn.txWorkingMap, TxHash)
+		kn.Unlock()
+	}()
+	if inBundle {
+		return txpool.ErrExistTransaction
+	}
+	if kn.Config.MaxTransactionBytes > 0 {
+		if Size, err := txpool.TransactionSize(tx, sigs); err != nil {
+			return err
+		} else if Size > kn.Config.MaxTransactionBytes {
+			return ErrExceedTransactionSize
+		}
+	}
 
 	if kn.txPool.IsExist(TxHash) {
 		return txpool.ErrExistTransaction
@@ -477,7 +617,7 @@ func (kn *Kernel) AddTransaction(tx transaction.Transaction, sigs []common.Signa
 		seq := loader.Seq(atx.From())
 		if atx.Seq() <= seq {
 			return ErrPastSeq
-		} else if atx.Seq() > seq+100 {
+		} else if atx.Seq() > seq+kn.maxFutureSeqGap() {
 			return ErrTooFarSeq
 		}
 	} else if utx, is := tx.(txpool.UTXOTransaction); is {
@@ -489,25 +629,50 @@ func (kn *Kernel) AddTransaction(tx transaction.Transaction, sigs []common.Signa
 			}
 		}
 	}
-	signers := make([]common.PublicHash, 0, len(sigs))
-	for _, sig := range sigs {
-		pubkey, err := common.RecoverPubkey(TxHash, sig)
-		if err != nil {
+	if signers == nil {
+		var err error
+		if signers, err = recoverSigners(TxHash, sigs); err != nil {
 			return err
 		}
-		signers = append(signers, common.NewPublicHash(pubkey))
 	}
 	if err := loader.Transactor().Validate(loader, tx, signers); err != nil {
 		return err
 	}
+	if transaction.IsExpired(tx, loader.TargetHeight(), uint64(time.Now().UnixNano())) {
+		return transaction.ErrExpiredTransaction
+	}
 	for _, eh := range kn.eventHandlers {
 		if err := eh.OnPushTransaction(kn, tx, sigs); err != nil {
 			return err
 		}
 	}
-	if err := kn.txPool.Push(tx, sigs); err != nil {
+	removed, err := kn.txPool.PushOrReplace(item)
+	if err != nil {
 		return err
 	}
+	if len(removed) > 0 {
+		TxHashes := make([]hash.Hash256, 0, len(removed))
+		for _, v := range removed {
+			kn.txQueue.Remove(string(v.TxHash[:]))
+			TxHashes = append(TxHashes, v.TxHash)
+		}
+		kn.Lock()
+		for _, TxHash := range TxHashes {
+			delete(kn.txWorkingMap, TxHash)
+			delete(kn.txSignersMap, TxHash)
+		}
+		kn.Unlock()
+		if kn.Config.PersistTxPool {
+			if err := kn.store.DeletePoolItems(TxHashes); err != nil {
+				kn.DebugLog("Kernel", "Delete Pool Items Failed :", err)
+			}
+		}
+	}
+	if kn.Config.PersistTxPool {
+		if err := kn.store.StorePoolItem(item); err != nil {
+			kn.DebugLog("Kernel", "Store Pool Item Failed :", TxHash.String(), err)
+		}
+	}
 	kn.txQueue.Push(string(TxHash[:]), &message_def.TransactionMessage{
 		Tx:   tx,
 		Sigs: sigs,
@@ -519,17 +684,184 @@ func (kn *Kernel) AddTransaction(tx transaction.Transaction, sigs []common.Signa
 
 	kn.Lock()
 	kn.txSignersMap[TxHash] = signers
-	delete(kn.txWorkingMap, TxHash)
 	kn.Unlock()
 
 	return nil
 }
 
+// Rewind rolls the chain back to the given height and reloads the consensus and the reward state of it
+func (kn *Kernel) Rewind(height uint32) error {
+	kn.closeLock.RLock()
+	defer kn.closeLock.RUnlock()
+	if kn.isClose {
+		return ErrKernelClosed
+	}
+
+	kn.Lock()
+	// the consensus and the reward state are reloaded even if the rewind is failed
+	// to keep them consistent with the stored state
+	RewindErr := kn.store.Rewind(height)
+	LoadErr := kn.loadSaveData()
+	kn.Unlock()
+
+	// pooled transactions can be invalid or valid again by the rolled back sequences and UTXOs
+	if err := kn.revalidateTxPool(); err != nil {
+		return err
+	}
+	if LoadErr != nil {
+		return LoadErr
+	}
+	if RewindErr != nil {
+		return RewindErr
+	}
+	kn.DebugLog("Kernel", "Rewinded :", kn.store.Height(), kn.store.LastHash().String())
+	return nil
+}
+
+// revalidateTxPool removes all items and bundles and pushes them again after validating them with the current state
+func (kn *Kernel) revalidateTxPool() error {
+	items := kn.txPool.Clear()
+	for _, item := range items {
+		kn.txQueue.Remove(string(item.TxHash[:]))
+	}
+	kn.Lock()
+	bundles := make([]*txBundle, 0, len(kn.bundleMap))
+	for _, bd := range kn.bundleMap {
+		bundles = append(bundles, bd)
+	}
+	kn.txSignersMap = map[hash.Hash256][]common.PublicHash{}
+	kn.bundleMap = map[hash.Hash256]*txBundle{}
+	kn.bundleTxMap = map[hash.Hash256]hash.Hash256{}
+	kn.Unlock()
+
+	removed := []hash.Hash256{}
+	for _, item := range items {
+		if err := kn.addTransaction(item, nil); err != nil {
+			removed = append(removed, item.TxHash)
+		}
+	}
+	if kn.Config.PersistTxPool && len(removed) > 0 {
+		if err := kn.store.DeletePoolItems(removed); err != nil {
+			return err
+		}
+	}
+
+	sort.Slice(bundles, func(i, j int) bool {
+		return bundles[i].PushedAt < bundles[j].PushedAt
+	})
+	return kn.restoreBundles(bundles, time.Now().UnixNano())
+}
+
+func (kn *Kernel) loadSaveData() error {
+	if SaveData := kn.store.StateCustomData("consensus"); SaveData == nil {
+		return ErrNotExistConsensusSaveData
+	} else if err := kn.cs.LoadFromSaveData(SaveData); err != nil {
+		return err
+	}
+	if SaveData := kn.store.StateCustomData("reward"); SaveData == nil {
+		return ErrNotExistRewardSaveData
+	} else if err := kn.rd.LoadFromSaveData(SaveData); err != nil {
+		return err
+	}
+	return nil
+}
+
+// TransactionByHash returns the transaction and its coordinate by the hash from the transaction index
+func (kn *Kernel) TransactionByHash(TxHash hash.Hash256) (transaction.Transaction, *common.Coordinate, error) {
+	kn.closeLock.RLock()
+	defer kn.closeLock.RUnlock()
+	if kn.isClose {
+		return nil, nil, ErrKernelClosed
+	}
+
+	return kn.store.TransactionByHash(TxHash)
+}
+
+// TransactionsByAddress returns coordinates of transactions related to the address after the cursor from the transaction index
+func (kn *Kernel) TransactionsByAddress(addr common.Address, cursor *common.Coordinate, limit int) ([]*common.Coordinate, error) {
+	kn.closeLock.RLock()
+	defer kn.closeLock.RUnlock()
+	if kn.isClose {
+		return nil, ErrKernelClosed
+	}
+
+	return kn.store.TransactionsByAddress(addr, cursor, limit)
+}
+
+// TransactionsByPublicHash returns coordinates of transactions related to utxos of the public hash after the cursor from the transaction index
+func (kn *Kernel) TransactionsByPublicHash(pubhash common.PublicHash, cursor *common.Coordinate, limit int) ([]*common.Coordinate, error) {
+	kn.closeLock.RLock()
+	defer kn.closeLock.RUnlock()
+	if kn.isClose {
+		return nil, ErrKernelClosed
+	}
+
+	return kn.store.TransactionsByPublicHash(pubhash, cursor, limit)
+}
+
+// StateProof returns the value of the state key and its proof from the state tree of the current height
+// The state of the height is committed to the StateRootHash of the header of the height + MaxBlocksPerFormulator
+func (kn *Kernel) StateProof(key []byte) ([]byte, *statetree.Proof, uint32, error) {
+	kn.closeLock.RLock()
+	defer kn.closeLock.RUnlock()
+	if kn.isClose {
+		return nil, nil, 0, ErrKernelClosed
+	}
+
+	return kn.store.Prove(key)
+}
+
+// stateRootHeight returns the height of the state that is committed to the header of the height
+// The previous blocks in the turn of the formulator are not stored when the block is generated,
+// so the header commits the state of MaxBlocksPerFormulator blocks before
+func (kn *Kernel) stateRootHeight(height uint32) uint32 {
+	if height <= kn.Config.MaxBlocksPerFormulator {
+		return 0
+	}
+	return height - kn.Config.MaxBlocksPerFormulator
+}
+
 // HasTransaction validate the transaction and push it to the transaction pool
 func (kn *Kernel) HasTransaction(TxHash hash.Hash256) bool {
 	return kn.txPool.IsExist(TxHash)
 }
 
+// TxPoolStats returns the current status of the transaction pool
+func (kn *Kernel) TxPoolStats() *txpool.Stats {
+	return kn.txPool.Stats()
+}
+
+// PendingTransactions returns snapshots of transactions in the transaction pool that can be included to the next block
+func (kn *Kernel) PendingTransactions() []*txpool.ItemInfo {
+	return kn.txPool.Pending(kn.Loader())
+}
+
+// QueuedTransactions returns snapshots of transactions in the transaction pool that wait for the previous sequence of the address
+func (kn *Kernel) QueuedTransactions() []*txpool.ItemInfo {
+	return kn.txPool.Queued(kn.Loader())
+}
+
+// PendingTransactionsByAddress returns snapshots of pending and queued transactions of the address in the transaction pool
+func (kn *Kernel) PendingTransactionsByAddress(addr common.Address) ([]*txpool.ItemInfo, []*txpool.ItemInfo) {
+	return kn.txPool.PendingByAddress(addr, kn.Loader())
+}
+
+// blockBuilder returns the block builder of the config or the default one
+func (kn *Kernel) blockBuilder() BlockBuilder {
+	if kn.Config.BlockBuilder != nil {
+		return kn.Config.BlockBuilder
+	}
+	return NewDefaultBlockBuilder()
+}
+
+// maxFutureSeqGap returns the maximum gap between the sequence of the account and the sequence of the pushed transaction
+func (kn *Kernel) maxFutureSeqGap() uint64 {
+	if kn.Config.MaxFutureSeqGap > 0 {
+		return kn.Config.MaxFutureSeqGap
+	}
+	return defaultMaxFutureSeqGap
+}
+
 func (kn *Kernel) contextByBlock(b *block.Block) (*data.Context, error) {
 	if err := kn.validateBlockBody(b); err != nil {
 		return nil, err
@@ -554,21 +886,51 @@ func (kn *Kernel) contextByBlock(b *block.Block) (*data.Context, error) {
 		}
 		ctx.RemoveLockedBalance(lb)
 	}
-	for i, tx := range b.Body.Transactions {
-		if _, err := ctx.Transactor().Execute(ctx, tx, &common.Coordinate{Height: b.Header.Height(), Index: uint16(i)}); err != nil {
-			return nil, err
-		}
+	if err := kn.cs.ApplyJailedFormulators(ctx, b.Header.TimeoutCount); err != nil {
+		return nil, err
+	}
+	if _, err := ctx.Transactor().ExecuteTransactions(ctx, b.Body.Transactions, b.Header.Height(), 0, false); err != nil {
+		return nil, err
 	}
 
 	if ctx.StackSize() > 1 {
 		return nil, ErrDirtyContext
 	}
 	if !b.Header.ContextHash.Equal(ctx.Hash()) {
+		kn.reportDivergence(NewDivergenceReport(b, ctx))
 		return nil, ErrInvalidAppendContextHash
 	}
+	if b.Header.Version() >= block.StateRootHashVersion {
+		if root, err := kn.store.StateRootHash(kn.stateRootHeight(b.Header.Height())); err != nil {
+			return nil, err
+		} else if !b.Header.StateRootHash.Equal(root) {
+			return nil, ErrInvalidStateRootHash
+		}
+	}
 	return ctx, nil
 }
 
+// reportDivergence saves the report to the debug directory and notifies it to event handlers
+func (kn *Kernel) reportDivergence(report *DivergenceReport) {
+	if len(kn.Config.DebugDir) > 0 {
+		if err := report.Save(kn.Config.DebugDir); err != nil {
+			kn.DebugLog("Kernel", "Divergence Report Save Failed :", report.Height, err)
+		}
+	}
+	for _, eh := range kn.eventHandlers {
+		eh.OnContextDivergence(kn, report)
+	}
+}
+
+// txPoolItemLifetime is the time that a transaction stays in the transaction pool
+const txPoolItemLifetime = 3600 * time.Second
+
+// defaultMaxFutureSeqGap is the maximum gap between the sequence of the account and the sequence of the pushed transaction when it is not configured
+const defaultMaxFutureSeqGap = 100
+
+// generateBatchSize is the maximum number of transactions that are executed together when generating a block
+const generateBatchSize = 256
+
 // GenerateBlock generate a next block and its signature using transactions in the pool
 func (kn *Kernel) GenerateBlock(ctx *data.Context, TimeoutCount uint32, Timestamp uint64, Formulator common.Address) (*block.Block, error) {
 	kn.closeLock.RLock()
@@ -595,57 +957,177 @@ func (kn *Kernel) GenerateBlock(ctx *data.Context, TimeoutCount uint32, Timestam
 		},
 	}
 
-	timer := time.NewTimer(200 * time.Millisecond)
+	builder := kn.blockBuilder()
+	MaxBytes := builder.MaxBytes()
+	if MaxBytes <= 0 {
+		MaxBytes = math.MaxInt64
+	}
+	if kn.Config.MaxBlockBytes > 0 {
+		// the size of the header is fixed and the body has the count of transactions
+		var buffer bytes.Buffer
+		if _, err := b.Header.WriteTo(&buffer); err != nil {
+			return nil, err
+		}
+		if Remain := kn.Config.MaxBlockBytes - int64(buffer.Len()) - 2; Remain < MaxBytes {
+			MaxBytes = Remain
+		}
+	}
+	if err := kn.cs.ApplyJailedFormulators(ctx, TimeoutCount); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(builder.TimeBudget())
 	TxHashes := make([]hash.Hash256, 0, 65536)
 	TxHashes = append(TxHashes, b.Header.PrevHash())
 
+	// bundles are appended before transactions of the pool to include them atomically
+	TxHashes, Bytes, err := kn.appendBundles(ctx, b, TxHashes, MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	included := []*txpool.PoolItem{}
+	expired := []hash.Hash256{}
+	notValidYet := []*txpool.PoolItem{}
+	var items []*txpool.PoolItem
+	isGenerated := false
+	isPoolLocked := true
 	kn.txPool.Lock() // Prevent delaying from TxPool.Push
+	// popped items that are not executed yet or included are returned to the pool when the block is not generated by an error
+	defer func() {
+		if isGenerated {
+			return
+		}
+		if !isPoolLocked {
+			kn.txPool.Lock()
+		}
+		for _, item := range included {
+			kn.txPool.UnsafeRestore(item)
+		}
+		for _, item := range items {
+			kn.txPool.UnsafeRestore(item)
+		}
+		for _, item := range notValidYet {
+			kn.txPool.UnsafeRestore(item)
+		}
+		kn.txPool.Unlock()
+	}()
 TxLoop:
 	for {
 		select {
 		case <-timer.C:
 			break TxLoop
 		default:
+			// transactions of the batch are popped before executing them, so an account has one transaction in the batch
+			MaxCount := kn.Config.MaxTransactionsPerBlock + 1 - len(TxHashes)
+			if MaxCount > generateBatchSize {
+				MaxCount = generateBatchSize
+			}
+			if MaxCount <= 0 || Bytes >= MaxBytes {
+				break TxLoop
+			}
 			sn := ctx.Snapshot()
-			item := kn.txPool.UnsafePop(ctx)
+			selected := builder.Select(kn.txPool, ctx, MaxCount, MaxBytes-Bytes)
 			ctx.Revert(sn)
-			if item == nil {
+			if len(selected) == 0 {
 				break TxLoop
 			}
-			idx := uint16(len(b.Body.Transactions))
-			if _, err := ctx.Transactor().Execute(ctx, item.Transaction, &common.Coordinate{Height: ctx.TargetHeight(), Index: idx}); err != nil {
-				log.Println(err)
+			items = []*txpool.PoolItem{}
+			txs := []transaction.Transaction{}
+			for _, item := range selected {
+				// the expired item is dropped and the item that is not valid yet is restored after the generation
+				if err := transaction.CheckWindow(item.Transaction, b.Header.Height(), Timestamp); err != nil {
+					if err == transaction.ErrNotValidYet {
+						notValidYet = append(notValidYet, item)
+					} else {
+						log.Println(err)
+						expired = append(expired, item.TxHash)
+					}
+					continue
+				}
+				items = append(items, item)
+				txs = append(txs, item.Transaction)
+			}
+			if len(items) == 0 {
 				continue
 			}
+			idx := uint16(len(b.Body.Transactions))
+			errs, err := ctx.Transactor().ExecuteTransactions(ctx, txs, ctx.TargetHeight(), idx, true)
+			if err != nil {
+				return nil, err
+			}
+			for i, item := range items {
+				if errs[i] != nil {
+					log.Println(errs[i])
+					continue
+				}
 
-			b.Body.Transactions = append(b.Body.Transactions, item.Transaction)
-			b.Body.TransactionSignatures = append(b.Body.TransactionSignatures, item.Signatures)
+				b.Body.Transactions = append(b.Body.Transactions, item.Transaction)
+				b.Body.TransactionSignatures = append(b.Body.TransactionSignatures, item.Signatures)
+				included = append(included, item)
 
-			TxHashes = append(TxHashes, item.TxHash)
+				TxHashes = append(TxHashes, item.TxHash)
+				Bytes += item.Size
+			}
+			items = nil
 
 			if len(TxHashes) > kn.Config.MaxTransactionsPerBlock {
 				break TxLoop
 			}
 		}
 	}
+	for _, item := range notValidYet {
+		kn.txPool.UnsafeRestore(item)
+	}
+	notValidYet = nil
+	isPoolLocked = false
 	kn.txPool.Unlock() // Prevent delaying from TxPool.Push
 
+	if len(expired) > 0 {
+		kn.Lock()
+		for _, TxHash := range expired {
+			kn.txQueue.Remove(string(TxHash[:]))
+			delete(kn.txSignersMap, TxHash)
+		}
+		kn.Unlock()
+		if kn.Config.PersistTxPool {
+			if err := kn.store.DeletePoolItems(expired); err != nil {
+				kn.DebugLog("Kernel", "Delete Pool Items Failed :", err)
+			}
+		}
+	}
+
 	if ctx.StackSize() > 1 {
 		return nil, ErrDirtyContext
 	}
 	b.Header.ContextHash = ctx.Hash()
+	if b.Header.Version() >= block.StateRootHashVersion {
+		if root, err := kn.store.StateRootHash(kn.stateRootHeight(b.Header.Height())); err != nil {
+			return nil, err
+		} else {
+			b.Header.StateRootHash = root
+		}
+	}
 
 	if h, err := level.BuildLevelRoot(TxHashes); err != nil {
 		return nil, err
 	} else {
 		b.Header.LevelRootHash = h
 	}
+	isGenerated = true
 	return b, nil
 }
 
 func (kn *Kernel) validateBlockBody(b *block.Block) error {
 	loader := kn.Loader()
 
+	if kn.Config.MaxBlockBytes > 0 {
+		if Size, err := b.Size(); err != nil {
+			return err
+		} else if Size > kn.Config.MaxBlockBytes {
+			return ErrExceedBlockSize
+		}
+	}
+
 	var wg sync.WaitGroup
 	cpuCnt := runtime.NumCPU()
 	if len(b.Body.Transactions) < 1000 {
@@ -672,6 +1154,11 @@ func (kn *Kernel) validateBlockBody(b *block.Block) error {
 				TxHash := tx.Hash()
 				TxHashes[sidx+q+1] = TxHash
 
+				if err := transaction.CheckWindow(tx, b.Header.Height(), b.Header.Timestamp()); err != nil {
+					errs <- err
+					return
+				}
+
 				signers, has := kn.txSignersMap[TxHash]
 				if !has {
 					signers = make([]common.PublicHash, 0, len(sigs))
@@ -711,7 +1198,13 @@ func (kn *Kernel) OnItemExpired(Interval time.Duration, Key string, Item interfa
 		eh.DoTransactionBroadcast(kn, msg)
 	}
 	if IsLast {
-		kn.txPool.Remove(msg.Tx.Hash(), msg.Tx)
+		TxHash := msg.Tx.Hash()
+		kn.txPool.Remove(TxHash, msg.Tx)
+		if kn.Config.PersistTxPool {
+			if err := kn.store.DeletePoolItems([]hash.Hash256{TxHash}); err != nil {
+				kn.DebugLog("Kernel", "Delete Pool Items Failed :", err)
+			}
+		}
 	}
 }
 
@@ -0,0 +1,207 @@
+package kernel
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/common/util"
+	"github.com/fletaio/core/db"
+	"github.com/fletaio/core/message_def"
+	"github.com/fletaio/core/txpool"
+)
+
+// StorePoolItem stores the item of the transaction pool to the journal to restore it after the restart
+// The journal is not a part of the chain state, so it is not recorded to the undo journal
+func (st *Store) StorePoolItem(item *txpool.PoolItem) error {
+	st.closeLock.RLock()
+	defer st.closeLock.RUnlock()
+	if st.isClose {
+		return ErrStoreClosed
+	}
+
+	var buffer bytes.Buffer
+	msg := &message_def.TransactionMessage{
+		Tx:   item.Transaction,
+		Sigs: item.Signatures,
+	}
+	if _, err := msg.WriteTo(&buffer); err != nil {
+		return err
+	}
+	if _, err := util.WriteUint64(&buffer, uint64(item.PushedAt)); err != nil {
+		return err
+	}
+	return st.db.Update(func(txn db.Txn) error {
+		return txn.Set(toPoolItemKey(item.TxHash), buffer.Bytes())
+	})
+}
+
+// DeletePoolItems deletes items of the transaction hashes from the journal of the transaction pool
+func (st *Store) DeletePoolItems(TxHashes []hash.Hash256) error {
+	st.closeLock.RLock()
+	defer st.closeLock.RUnlock()
+	if st.isClose {
+		return ErrStoreClosed
+	}
+
+	return st.db.Update(func(txn db.Txn) error {
+		for _, TxHash := range TxHashes {
+			if err := txn.Delete(toPoolItemKey(TxHash)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// PoolItems returns items in the journal of the transaction pool by the order of the pushed time
+func (st *Store) PoolItems() ([]*txpool.PoolItem, error) {
+	st.closeLock.RLock()
+	defer st.closeLock.RUnlock()
+	if st.isClose {
+		return nil, ErrStoreClosed
+	}
+
+	list := []*txpool.PoolItem{}
+	invalidKeys := [][]byte{}
+	if err := st.db.View(func(txn db.Txn) error {
+		return txn.Iterate(tagPoolItem, tagPoolItem, func(key []byte, value []byte) error {
+			r := bytes.NewReader(value)
+			msg := &message_def.TransactionMessage{
+				Tran: st.transactor,
+			}
+			// the item that cannot be decoded is removed from the journal
+			if _, err := msg.ReadFrom(r); err != nil {
+				invalidKeys = append(invalidKeys, key)
+				return nil
+			}
+			PushedAt, _, err := util.ReadUint64(r)
+			if err != nil {
+				invalidKeys = append(invalidKeys, key)
+				return nil
+			}
+			list = append(list, &txpool.PoolItem{
+				Transaction: msg.Tx,
+				TxHash:      msg.Tx.Hash(),
+				Signatures:  msg.Sigs,
+				PushedAt:    int64(PushedAt),
+			})
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+	if len(invalidKeys) > 0 {
+		if err := st.db.Update(func(txn db.Txn) error {
+			for _, key := range invalidKeys {
+				if err := txn.Delete(key); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].PushedAt < list[j].PushedAt
+	})
+	return list, nil
+}
+
+// storePoolBundle stores the bundle to the journal of the transaction pool to restore it after the restart
+func (st *Store) storePoolBundle(bd *txBundle) error {
+	st.closeLock.RLock()
+	defer st.closeLock.RUnlock()
+	if st.isClose {
+		return ErrStoreClosed
+	}
+
+	var buffer bytes.Buffer
+	msg := &message_def.BundleMessage{
+		Txs:  bd.Transactions,
+		Sigs: bd.Signatures,
+	}
+	if _, err := msg.WriteTo(&buffer); err != nil {
+		return err
+	}
+	if _, err := util.WriteUint64(&buffer, uint64(bd.PushedAt)); err != nil {
+		return err
+	}
+	return st.db.Update(func(txn db.Txn) error {
+		return txn.Set(toPoolBundleKey(bd.Hash), buffer.Bytes())
+	})
+}
+
+// deletePoolBundles deletes bundles of the bundle hashes from the journal of the transaction pool
+func (st *Store) deletePoolBundles(BundleHashes []hash.Hash256) error {
+	st.closeLock.RLock()
+	defer st.closeLock.RUnlock()
+	if st.isClose {
+		return ErrStoreClosed
+	}
+
+	return st.db.Update(func(txn db.Txn) error {
+		for _, BundleHash := range BundleHashes {
+			if err := txn.Delete(toPoolBundleKey(BundleHash)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// poolBundles returns bundles in the journal of the transaction pool by the order of the pushed time
+// The hash of the bundle is calculated again when it is added to the kernel
+func (st *Store) poolBundles() ([]*txBundle, error) {
+	st.closeLock.RLock()
+	defer st.closeLock.RUnlock()
+	if st.isClose {
+		return nil, ErrStoreClosed
+	}
+
+	list := []*txBundle{}
+	invalidKeys := [][]byte{}
+	if err := st.db.View(func(txn db.Txn) error {
+		return txn.Iterate(tagPoolBundle, tagPoolBundle, func(key []byte, value []byte) error {
+			r := bytes.NewReader(value)
+			msg := &message_def.BundleMessage{
+				Tran: st.transactor,
+			}
+			// the bundle that cannot be decoded is removed from the journal
+			if _, err := msg.ReadFrom(r); err != nil {
+				invalidKeys = append(invalidKeys, key)
+				return nil
+			}
+			PushedAt, _, err := util.ReadUint64(r)
+			if err != nil {
+				invalidKeys = append(invalidKeys, key)
+				return nil
+			}
+			list = append(list, &txBundle{
+				Transactions: msg.Txs,
+				Signatures:   msg.Sigs,
+				PushedAt:     int64(PushedAt),
+			})
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+	if len(invalidKeys) > 0 {
+		if err := st.db.Update(func(txn db.Txn) error {
+			for _, key := range invalidKeys {
+				if err := txn.Delete(key); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].PushedAt < list[j].PushedAt
+	})
+	return list, nil
+}
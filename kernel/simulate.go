@@ -0,0 +1,118 @@
+package kernel
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/core/account"
+	"github.com/fletaio/core/amount"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/core/event"
+	"github.com/fletaio/core/transaction"
+)
+
+// SimulationResult is the result of the transaction that is executed on the throwaway context
+// Accounts contains accounts that are loaded or updated by the transaction
+type SimulationResult struct {
+	Result          interface{}
+	Events          []event.Event
+	Fee             *amount.Amount
+	Accounts        []account.Account
+	CreatedAccounts []account.Account
+	DeletedAccounts []common.Address
+	CreatedUTXOs    map[uint64]*transaction.TxOut
+	SpentUTXOs      []uint64
+}
+
+// Simulate validates and executes the transaction on the current state without updating the pool and the store
+// If bPending is true, pending transactions of the pool are executed before the transaction
+func (kn *Kernel) Simulate(tx transaction.Transaction, sigs []common.Signature, bPending bool) (*SimulationResult, error) {
+	kn.closeLock.RLock()
+	defer kn.closeLock.RUnlock()
+	if kn.isClose {
+		return nil, ErrKernelClosed
+	}
+
+	TxHash := tx.Hash()
+	signers, err := recoverSigners(TxHash, sigs)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := data.NewContext(kn.store)
+	var idx uint16
+	if bPending {
+		txs := []transaction.Transaction{}
+		for _, info := range kn.txPool.Pending(ctx) {
+			if item := kn.txPool.Item(info.TxHash); item != nil && !info.TxHash.Equal(TxHash) {
+				txs = append(txs, item.Transaction)
+			}
+		}
+		if len(txs) > 0 {
+			errs, err := ctx.Transactor().ExecuteTransactions(ctx, txs, ctx.TargetHeight(), 0, true)
+			if err != nil {
+				return nil, err
+			}
+			for _, err := range errs {
+				if err == nil {
+					idx++
+				}
+			}
+		}
+	}
+
+	// the transaction is executed on the new snapshot, so the top of the context has only its changes
+	ctx.Snapshot()
+	if err := ctx.Transactor().Validate(ctx, tx, signers); err != nil {
+		return nil, err
+	}
+	ret, err := ctx.Transactor().Execute(ctx, tx, &common.Coordinate{Height: ctx.TargetHeight(), Index: idx})
+	if err != nil {
+		return nil, err
+	}
+	return newSimulationResult(ret, ctx.Transactor().Fee(tx), ctx.Top()), nil
+}
+
+func newSimulationResult(ret interface{}, Fee *amount.Amount, ctd *data.ContextData) *SimulationResult {
+	res := &SimulationResult{
+		Result:          ret,
+		Events:          ctd.Events,
+		Fee:             Fee,
+		Accounts:        []account.Account{},
+		CreatedAccounts: []account.Account{},
+		DeletedAccounts: []common.Address{},
+		CreatedUTXOs:    map[uint64]*transaction.TxOut{},
+		SpentUTXOs:      []uint64{},
+	}
+	for _, acc := range ctd.AccountMap {
+		res.Accounts = append(res.Accounts, acc)
+	}
+	sort.Slice(res.Accounts, func(i, j int) bool {
+		a, b := res.Accounts[i].Address(), res.Accounts[j].Address()
+		return bytes.Compare(a[:], b[:]) < 0
+	})
+	for _, acc := range ctd.CreatedAccountMap {
+		res.CreatedAccounts = append(res.CreatedAccounts, acc)
+	}
+	sort.Slice(res.CreatedAccounts, func(i, j int) bool {
+		a, b := res.CreatedAccounts[i].Address(), res.CreatedAccounts[j].Address()
+		return bytes.Compare(a[:], b[:]) < 0
+	})
+	for addr := range ctd.DeletedAccountMap {
+		res.DeletedAccounts = append(res.DeletedAccounts, addr)
+	}
+	sort.Slice(res.DeletedAccounts, func(i, j int) bool {
+		return bytes.Compare(res.DeletedAccounts[i][:], res.DeletedAccounts[j][:]) < 0
+	})
+	for id, vout := range ctd.CreatedUTXOMap {
+		res.CreatedUTXOs[id] = vout
+	}
+	for id := range ctd.DeletedUTXOMap {
+		res.SpentUTXOs = append(res.SpentUTXOs, id)
+	}
+	sort.Slice(res.SpentUTXOs, func(i, j int) bool {
+		return res.SpentUTXOs[i] < res.SpentUTXOs[j]
+	})
+	return res
+}
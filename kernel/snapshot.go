@@ -0,0 +1,433 @@
+package kernel
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"os"
+
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/common/util"
+	"github.com/fletaio/core/account"
+	"github.com/fletaio/core/block"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/core/db"
+	"github.com/fletaio/core/statetree"
+)
+
+const snapshotMagic = "FLETA_SNAPSHOT"
+const snapshotVersion = 2
+
+// importBatchSize is the number of the records that are imported in one transaction
+const importBatchSize = 1000
+
+// snapshotPrefixes has only prefixes of state keys to verify all records by the state root
+// The name index and the locked balance height index are rebuilt from the imported state
+var snapshotPrefixes = [][]byte{
+	tagAccount,
+	tagAccountSeq,
+	tagAccountData,
+	tagUTXO,
+	tagLockedBalance,
+	tagStateCustomData,
+}
+
+// ExportSnapshot writes the state of the snapshot height with the headers that follows it
+// The state root of the snapshot height is committed to the header of the height + the state root lag,
+// so the snapshot height is the current height - the state root lag and the state of it is built by the undo journals
+func (st *Store) ExportSnapshot(w io.Writer) error {
+	st.closeLock.RLock()
+	defer st.closeLock.RUnlock()
+	if st.isClose {
+		return ErrStoreClosed
+	}
+
+	return st.db.View(func(txn db.Txn) error {
+		var current uint32
+		if value, err := txn.Get([]byte("height")); err != nil {
+			return err
+		} else {
+			current = util.BytesToUint32(value)
+		}
+		if current <= st.stateRootLag {
+			return ErrInvalidSnapshotHeight
+		}
+		height := current - st.stateRootLag
+
+		overlay := map[string]*undoEntry{}
+		for h := current; h > height; h-- {
+			value, err := txn.Get(toHeightUndoKey(h))
+			if err != nil {
+				if err == db.ErrNotExistKey {
+					return ErrNotExistUndoData
+				} else {
+					return err
+				}
+			}
+			entries, err := readUndoEntries(value)
+			if err != nil {
+				return err
+			}
+			for _, e := range entries {
+				overlay[string(e.Key)] = e
+			}
+		}
+
+		hasher := sha256.New()
+		mw := io.MultiWriter(w, hasher)
+		if _, err := util.WriteString(mw, snapshotMagic); err != nil {
+			return err
+		}
+		if _, err := util.WriteUint16(mw, snapshotVersion); err != nil {
+			return err
+		}
+		if _, err := util.WriteUint32(mw, height); err != nil {
+			return err
+		}
+		if value, err := txn.Get(toHeightHashKey(0)); err != nil {
+			return err
+		} else if _, err := util.WriteBytes(mw, value); err != nil {
+			return err
+		}
+		if _, err := util.WriteUint32(mw, current-height+1); err != nil {
+			return err
+		}
+		for h := height; h <= current; h++ {
+			value, err := txn.Get(toHeightHeaderKey(h))
+			if err != nil {
+				return err
+			}
+			if _, err := util.WriteBytes(mw, value); err != nil {
+				return err
+			}
+		}
+
+		for _, prefix := range snapshotPrefixes {
+			if err := txn.Iterate(prefix, prefix, func(key []byte, value []byte) error {
+				if e, has := overlay[string(key)]; has {
+					delete(overlay, string(key))
+					if !e.IsExist {
+						return nil
+					}
+					value = e.Value
+				}
+				return writeSnapshotRecord(mw, key, value)
+			}); err != nil {
+				return err
+			}
+		}
+		// keys that are deleted after the snapshot height
+		for k, e := range overlay {
+			if !e.IsExist {
+				continue
+			}
+			if isSnapshotKey(e.Key) {
+				if err := writeSnapshotRecord(mw, []byte(k), e.Value); err != nil {
+					return err
+				}
+			}
+		}
+		if err := writeSnapshotRecord(mw, nil, nil); err != nil {
+			return err
+		}
+
+		var checksum hash.Hash256
+		copy(checksum[:], hasher.Sum(nil))
+		if _, err := checksum.WriteTo(w); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func isSnapshotKey(key []byte) bool {
+	for _, prefix := range snapshotPrefixes {
+		if bytes.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeSnapshotRecord(w io.Writer, key []byte, value []byte) error {
+	if _, err := util.WriteBytes(w, key); err != nil {
+		return err
+	}
+	if len(key) > 0 {
+		if _, err := util.WriteBytes(w, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewStoreFromSnapshot returns a Store that imports the snapshot and verifies it by the trusted hash of the last header of it
+// The path should not exist or be empty and it is removed when the import is failed
+func NewStoreFromSnapshot(path string, version uint16, act *data.Accounter, tran *data.Transactor, evt *data.Eventer, r io.Reader, TrustedHash hash.Hash256, StateRootLag uint32) (*Store, error) {
+	if f, err := os.Open(path); err == nil {
+		names, err := f.Readdirnames(1)
+		f.Close()
+		if err != io.EOF || len(names) > 0 {
+			return nil, ErrNotEmptySnapshotPath
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	st, err := NewStore(path, version, act, tran, evt, false)
+	if err != nil {
+		os.RemoveAll(path)
+		return nil, err
+	}
+	st.stateRootLag = StateRootLag
+	if err := st.importSnapshot(r, TrustedHash); err != nil {
+		st.Close()
+		os.RemoveAll(path)
+		return nil, err
+	}
+	return st, nil
+}
+
+func (st *Store) importSnapshot(r io.Reader, TrustedHash hash.Hash256) error {
+	hasher := sha256.New()
+	tr := io.TeeReader(r, hasher)
+
+	if v, _, err := util.ReadString(tr); err != nil {
+		return err
+	} else if v != snapshotMagic {
+		return ErrInvalidSnapshot
+	}
+	if v, _, err := util.ReadUint16(tr); err != nil {
+		return err
+	} else if v != snapshotVersion {
+		return ErrInvalidSnapshotVersion
+	}
+	height, _, err := util.ReadUint32(tr)
+	if err != nil {
+		return err
+	}
+	var GenesisHash hash.Hash256
+	if bs, _, err := util.ReadBytes(tr); err != nil {
+		return err
+	} else if len(bs) != len(GenesisHash) {
+		return ErrInvalidSnapshot
+	} else {
+		copy(GenesisHash[:], bs)
+	}
+	HeaderCount, _, err := util.ReadUint32(tr)
+	if err != nil {
+		return err
+	}
+	if HeaderCount != st.stateRootLag+1 {
+		return ErrInvalidSnapshot
+	}
+	headers := make([]*block.Header, 0, HeaderCount)
+	for i := uint32(0); i < HeaderCount; i++ {
+		bs, _, err := util.ReadBytes(tr)
+		if err != nil {
+			return err
+		}
+		bh := &block.Header{}
+		if _, err := bh.ReadFrom(bytes.NewReader(bs)); err != nil {
+			return err
+		}
+		if bh.Height() != height+i {
+			return ErrInvalidSnapshot
+		}
+		if i > 0 && !bh.PrevHash().Equal(headers[i-1].Hash()) {
+			return ErrInvalidSnapshot
+		}
+		headers = append(headers, bh)
+	}
+	LastHeader := headers[len(headers)-1]
+	if !LastHeader.Hash().Equal(TrustedHash) {
+		return ErrInvalidSnapshotHash
+	}
+	// the state of the snapshot is verified by the state root hash of the last header
+	if LastHeader.Version() < block.StateRootHashVersion {
+		return ErrInvalidSnapshot
+	}
+
+	var root hash.Hash256
+	for {
+		kv := map[string][]byte{}
+		records := [][]byte{}
+		for len(records) < importBatchSize*2 {
+			key, _, err := util.ReadBytes(tr)
+			if err != nil {
+				return err
+			}
+			if len(key) == 0 {
+				break
+			}
+			value, _, err := util.ReadBytes(tr)
+			if err != nil {
+				return err
+			}
+			if !isSnapshotKey(key) {
+				return ErrInvalidSnapshot
+			}
+			records = append(records, key, value)
+			kv[string(key)] = value
+		}
+		if len(records) == 0 {
+			break
+		}
+		if err := st.db.Update(func(txn db.Txn) error {
+			for i := 0; i < len(records); i += 2 {
+				if err := txn.Set(records[i], records[i+1]); err != nil {
+					return err
+				}
+			}
+			NewRoot, nodes, stale, err := statetree.Update(&stateNodeStore{txn: txn}, root, kv)
+			if err != nil {
+				return err
+			}
+			// nodes of the tree of the previous batch are not used by any height
+			for _, h := range stale {
+				if err := txn.Delete(toStateNodeKey(h)); err != nil {
+					return err
+				}
+			}
+			for h, bs := range nodes {
+				if err := txn.Set(toStateNodeKey(h), bs); err != nil {
+					return err
+				}
+			}
+			root = NewRoot
+			return nil
+		}); err != nil {
+			return err
+		}
+		if len(records) < importBatchSize*2 {
+			break
+		}
+	}
+
+	var checksum hash.Hash256
+	copy(checksum[:], hasher.Sum(nil))
+	var Expected hash.Hash256
+	if _, err := Expected.ReadFrom(r); err != nil {
+		return err
+	}
+	if !checksum.Equal(Expected) {
+		return ErrInvalidSnapshotChecksum
+	}
+	if !root.Equal(LastHeader.StateRootHash) {
+		return ErrInvalidStateRootHash
+	}
+
+	if err := st.rebuildIndex(tagAccount, func(key []byte, value []byte) ([]byte, []byte, error) {
+		if len(value) == 0 {
+			return nil, nil, ErrInvalidSnapshot
+		}
+		acc, err := st.accounter.NewByType(account.Type(value[0]))
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := acc.ReadFrom(bytes.NewReader(value[1:])); err != nil {
+			return nil, nil, err
+		}
+		addr := acc.Address()
+		return toAccountNameKey(acc.Name()), addr[:], nil
+	}); err != nil {
+		return err
+	}
+	if err := st.rebuildIndex(tagLockedBalance, func(key []byte, value []byte) ([]byte, []byte, error) {
+		addr, UnlockHeight := fromLockedBalanceKey(key)
+		return toLockedBalanceHeightKey(UnlockHeight, addr), value, nil
+	}); err != nil {
+		return err
+	}
+
+	if err := st.db.Update(func(txn db.Txn) error {
+		if err := txn.Set(toHeightHashKey(0), GenesisHash[:]); err != nil {
+			return err
+		}
+		if err := txn.Set(toHashHeightKey(GenesisHash), util.Uint32ToBytes(0)); err != nil {
+			return err
+		}
+		Header := headers[0]
+		var buffer bytes.Buffer
+		if _, err := Header.WriteTo(&buffer); err != nil {
+			return err
+		}
+		if err := txn.Set(toHeightHeaderKey(height), buffer.Bytes()); err != nil {
+			return err
+		}
+		HeaderHash := Header.Hash()
+		if err := txn.Set(toHeightHashKey(height), HeaderHash[:]); err != nil {
+			return err
+		}
+		bsHeight := util.Uint32ToBytes(height)
+		if err := txn.Set(toHashHeightKey(HeaderHash), bsHeight); err != nil {
+			return err
+		}
+		if err := txn.Set([]byte("height"), bsHeight); err != nil {
+			return err
+		}
+		if err := txn.Set([]byte("prunedheight"), bsHeight); err != nil {
+			return err
+		}
+		// state roots of the previous heights are required to validate the following blocks
+		for _, bh := range headers[1:] {
+			if bh.Version() < block.StateRootHashVersion {
+				continue
+			}
+			var h uint32
+			if bh.Height() > st.stateRootLag {
+				h = bh.Height() - st.stateRootLag
+			}
+			if err := txn.Set(toHeightStateRootKey(h), bh.StateRootHash[:]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	st.prunedHeight = height
+	st.resetCache()
+	return nil
+}
+
+// rebuildIndex stores index records that are made from records of the prefix by the batch
+func (st *Store) rebuildIndex(prefix []byte, fn func(key []byte, value []byte) ([]byte, []byte, error)) error {
+	begin := prefix
+	for {
+		records := [][]byte{}
+		if err := st.db.View(func(txn db.Txn) error {
+			return txn.Iterate(begin, prefix, func(key []byte, value []byte) error {
+				IndexKey, IndexValue, err := fn(key, value)
+				if err != nil {
+					return err
+				}
+				records = append(records, IndexKey, IndexValue)
+				if len(records) >= importBatchSize*2 {
+					// the next batch begins from the key right after the last one
+					begin = append(key, 0)
+					return db.ErrStopIteration
+				}
+				return nil
+			})
+		}); err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		if err := st.db.Update(func(txn db.Txn) error {
+			for i := 0; i < len(records); i += 2 {
+				if err := txn.Set(records[i], records[i+1]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		if len(records) < importBatchSize*2 {
+			return nil
+		}
+	}
+}
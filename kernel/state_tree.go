@@ -0,0 +1,247 @@
+package kernel
+
+import (
+	"bytes"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/common/util"
+	"github.com/fletaio/core/db"
+	"github.com/fletaio/core/statetree"
+)
+
+// AccountStateKey returns the state key of the account
+// The value of it is the account type byte followed by the serialized account
+func AccountStateKey(addr common.Address) []byte {
+	return toAccountKey(addr)
+}
+
+// AccountSeqStateKey returns the state key of the sequence of the account
+// The value of it is the little endian uint64 sequence
+func AccountSeqStateKey(addr common.Address) []byte {
+	return toAccountSeqKey(addr)
+}
+
+// AccountDataStateKey returns the state key of the account data
+func AccountDataStateKey(addr common.Address, name []byte) []byte {
+	return toAccountDataKey(string(addr[:]) + string(name))
+}
+
+// UTXOStateKey returns the state key of the UTXO
+// The value of it is the serialized TxOut
+func UTXOStateKey(id uint64) []byte {
+	return toUTXOKey(id)
+}
+
+// LockedBalanceStateKey returns the state key of the locked balance
+// The value of it is the bytes of the locked amount
+func LockedBalanceStateKey(addr common.Address, UnlockHeight uint32) []byte {
+	return toLockedBalanceKey(addr, UnlockHeight)
+}
+
+// CustomDataStateKey returns the state key of the custom data that is stored with blocks
+func CustomDataStateKey(key string) []byte {
+	return toStateCustomDataKey(key)
+}
+
+func isStateKey(key []byte) bool {
+	if len(key) < 2 {
+		return false
+	}
+	tag := key[:2]
+	return bytes.Equal(tag, tagAccount) || bytes.Equal(tag, tagAccountSeq) || bytes.Equal(tag, tagAccountData) || bytes.Equal(tag, tagUTXO) || bytes.Equal(tag, tagLockedBalance) || bytes.Equal(tag, tagStateCustomData)
+}
+
+type stateNodeStore struct {
+	txn db.Txn
+}
+
+// Node returns the node of the state tree
+func (ns *stateNodeStore) Node(h hash.Hash256) ([]byte, error) {
+	value, err := ns.txn.Get(toStateNodeKey(h))
+	if err != nil {
+		if err == db.ErrNotExistKey {
+			return nil, statetree.ErrNotExistNode
+		} else {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+func stateRootHash(txn db.Txn, height uint32) (hash.Hash256, error) {
+	value, err := txn.Get(toHeightStateRootKey(height))
+	if err != nil {
+		return hash.Hash256{}, err
+	}
+	var root hash.Hash256
+	if _, err := root.ReadFrom(bytes.NewReader(value)); err != nil {
+		return hash.Hash256{}, err
+	}
+	return root, nil
+}
+
+// updateStateTree applies the state keys updated by the transaction to the state tree and stores the root of the height
+// It should be called after all state updates of the height are applied to the transaction
+func updateStateTree(ut *undoTxn, root hash.Hash256, height uint32) error {
+	keyMap := map[string]bool{}
+	for _, e := range ut.entries {
+		if isStateKey(e.Key) {
+			keyMap[string(e.Key)] = true
+		}
+	}
+
+	kv := map[string][]byte{}
+	for k := range keyMap {
+		value, err := ut.Get([]byte(k))
+		if err != nil {
+			if err != db.ErrNotExistKey {
+				return err
+			}
+			kv[k] = nil
+			continue
+		}
+		kv[k] = value
+	}
+	NewRoot, nodes, stale, err := statetree.Update(&stateNodeStore{txn: ut.txn}, root, kv)
+	if err != nil {
+		return err
+	}
+	// nodes are recorded to the undo journal to remove them by the rewind
+	// and the stale mark of the node is removed because it is used again
+	for h, bs := range nodes {
+		if err := ut.Set(toStateNodeKey(h), bs); err != nil {
+			return err
+		}
+		if _, err := ut.Get(toStaleNodeKey(h)); err != nil {
+			if err != db.ErrNotExistKey {
+				return err
+			}
+		} else if err := ut.Delete(toStaleNodeKey(h)); err != nil {
+			return err
+		}
+	}
+	// stale nodes are kept until the undo journal of the height is pruned because the rewind requires them
+	if len(stale) > 0 {
+		bsHeight := util.Uint32ToBytes(height)
+		list := make([]byte, 0, len(stale)*32)
+		for _, h := range stale {
+			if err := ut.Set(toStaleNodeKey(h), bsHeight); err != nil {
+				return err
+			}
+			list = append(list, h[:]...)
+		}
+		if err := ut.Set(toHeightStaleNodesKey(height), list); err != nil {
+			return err
+		}
+	}
+	if err := ut.Set(toHeightStateRootKey(height), NewRoot[:]); err != nil {
+		return err
+	}
+	return nil
+}
+
+// pruneStateNodes deletes nodes that became stale at the height and are not used again after it
+func pruneStateNodes(txn db.Txn, height uint32) error {
+	list, err := txn.Get(toHeightStaleNodesKey(height))
+	if err != nil {
+		if err == db.ErrNotExistKey {
+			return nil
+		} else {
+			return err
+		}
+	}
+	for i := 0; i+32 <= len(list); i += 32 {
+		var h hash.Hash256
+		copy(h[:], list[i:i+32])
+		value, err := txn.Get(toStaleNodeKey(h))
+		if err != nil {
+			if err == db.ErrNotExistKey {
+				continue
+			} else {
+				return err
+			}
+		}
+		if util.BytesToUint32(value) != height {
+			continue
+		}
+		if err := txn.Delete(toStaleNodeKey(h)); err != nil {
+			return err
+		}
+		if err := txn.Delete(toStateNodeKey(h)); err != nil {
+			return err
+		}
+	}
+	if err := txn.Delete(toHeightStaleNodesKey(height)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// StateRootHash returns the root hash of the state tree after storing the data of the height
+func (st *Store) StateRootHash(height uint32) (hash.Hash256, error) {
+	st.closeLock.RLock()
+	defer st.closeLock.RUnlock()
+	if st.isClose {
+		return hash.Hash256{}, ErrStoreClosed
+	}
+
+	var root hash.Hash256
+	if err := st.db.View(func(txn db.Txn) error {
+		h, err := stateRootHash(txn, height)
+		if err != nil {
+			return err
+		}
+		root = h
+		return nil
+	}); err != nil {
+		return hash.Hash256{}, err
+	}
+	return root, nil
+}
+
+// Prove returns the value of the state key and its proof from the state tree of the current height
+// The nil value means that the key is not exist and the proof can be verified by statetree.VerifyProof with the root of the returned height
+func (st *Store) Prove(key []byte) ([]byte, *statetree.Proof, uint32, error) {
+	st.closeLock.RLock()
+	defer st.closeLock.RUnlock()
+	if st.isClose {
+		return nil, nil, 0, ErrStoreClosed
+	}
+
+	if !isStateKey(key) {
+		return nil, nil, 0, ErrInvalidStateKey
+	}
+
+	var value []byte
+	var proof *statetree.Proof
+	var height uint32
+	if err := st.db.View(func(txn db.Txn) error {
+		bs, err := txn.Get([]byte("height"))
+		if err != nil {
+			return err
+		}
+		height = util.BytesToUint32(bs)
+
+		root, err := stateRootHash(txn, height)
+		if err != nil {
+			return err
+		}
+		if v, err := txn.Get(key); err != nil {
+			if err != db.ErrNotExistKey {
+				return err
+			}
+		} else {
+			value = v
+		}
+		p, err := statetree.Prove(&stateNodeStore{txn: txn}, root, key)
+		if err != nil {
+			return err
+		}
+		proof = p
+		return nil
+	}); err != nil {
+		return nil, nil, 0, err
+	}
+	return value, proof, height, nil
+}
@@ -2,14 +2,11 @@ package kernel
 
 import (
 	"bytes"
-	"os"
-	"path/filepath"
 	"sync"
-	"time"
+	"sync/atomic"
 
 	"github.com/fletaio/core/amount"
 
-	"github.com/dgraph-io/badger"
 	"github.com/fletaio/common"
 	"github.com/fletaio/common/hash"
 	"github.com/fletaio/common/util"
@@ -22,21 +19,37 @@ import (
 	"github.com/fletaio/framework/chain"
 )
 
+// storeFormatVersion is the version of the layout of stored keys
+// Version 1 is the layout of stores that have no stored version and they are migrated when the store is opened
+// Version 2 adds the state tree, moves the locked balance height index out of the locked balance keys that are committed to the state root,
+// commits the custom data of blocks to the state root and keeps names of all accounts in the name index
+const storeFormatVersion = 2
+
+// maxPruneHeightsPerBlock limits the number of pruned heights in one update to bound the size of the transaction
+const maxPruneHeightsPerBlock = 100
+
+// compactPrunedHeights is the number of pruned heights that triggers the compaction of the db
+const compactPrunedHeights = 1000
+
 // Store saves the target chain state
-// All updates are executed in one transaction with FileSync option
+// All updates are executed in one transaction of the db
 type Store struct {
 	sync.Mutex
-	db         *badger.DB
-	version    uint16
-	accounter  *data.Accounter
-	transactor *data.Transactor
-	eventer    *data.Eventer
-	SeqMapLock sync.Mutex
-	SeqMap     map[common.Address]uint64
-	cache      storeCache
-	ticker     *time.Ticker
-	closeLock  sync.RWMutex
-	isClose    bool
+	db           db.DB
+	version      uint16
+	accounter    *data.Accounter
+	transactor   *data.Transactor
+	eventer      *data.Eventer
+	SeqMapLock   sync.Mutex
+	SeqMap       map[common.Address]uint64
+	cache        storeCache
+	isIndexing   bool
+	keepBlocks   uint32
+	stateRootLag uint32
+	prunedHeight uint32
+	isCompacting int32
+	closeLock    sync.RWMutex
+	isClose      bool
 }
 
 type storeCache struct {
@@ -46,55 +59,86 @@ type storeCache struct {
 	heightData *chain.Data
 }
 
-// NewStore returns a Store
+// NewStore returns a Store that is based on the badger db of the path
 func NewStore(path string, version uint16, act *data.Accounter, tran *data.Transactor, evt *data.Eventer, bRecover bool) (*Store, error) {
 	if !act.ChainCoord().Equal(tran.ChainCoord()) {
 		return nil, ErrInvalidChainCoord
 	}
 
-	opts := badger.DefaultOptions
-	opts.Dir = path
-	opts.ValueDir = path
-	opts.Truncate = bRecover
-	opts.SyncWrites = true
-	lockfilePath := filepath.Join(opts.Dir, "LOCK")
-	os.MkdirAll(path, os.ModeDir)
-
-	os.Remove(lockfilePath)
-
-	db, err := badger.Open(opts)
+	bdb, err := db.NewBadgerDB(path, bRecover)
+	if err != nil {
+		return nil, err
+	}
+	st, err := NewStoreWithDB(bdb, version, act, tran, evt)
 	if err != nil {
+		bdb.Close()
 		return nil, err
 	}
+	return st, nil
+}
 
-	{
-	again:
-		if err := db.RunValueLogGC(0.7); err != nil {
-		} else {
-			goto again
+// NewStoreWithDB returns a Store that is based on the given db
+func NewStoreWithDB(kv db.DB, version uint16, act *data.Accounter, tran *data.Transactor, evt *data.Eventer) (*Store, error) {
+	if !act.ChainCoord().Equal(tran.ChainCoord()) {
+		return nil, ErrInvalidChainCoord
+	}
+
+	var formatVersion uint32
+	if err := kv.Update(func(txn db.Txn) error {
+		value, err := txn.Get([]byte("storeversion"))
+		if err != nil {
+			if err != db.ErrNotExistKey {
+				return err
+			}
+			if _, err := txn.Get([]byte("height")); err == nil {
+				formatVersion = 1
+				return nil
+			} else if err != db.ErrNotExistKey {
+				return err
+			}
+			formatVersion = storeFormatVersion
+			return txn.Set([]byte("storeversion"), util.Uint32ToBytes(storeFormatVersion))
+		}
+		formatVersion = util.BytesToUint32(value)
+		if formatVersion > storeFormatVersion {
+			return ErrIncompatibleStoreVersion
 		}
+		return nil
+	}); err != nil {
+		return nil, err
 	}
 
-	ticker := time.NewTicker(5 * time.Minute)
-	go func() {
-		for range ticker.C {
-		again:
-			if err := db.RunValueLogGC(0.7); err != nil {
+	var prunedHeight uint32
+	if err := kv.View(func(txn db.Txn) error {
+		value, err := txn.Get([]byte("prunedheight"))
+		if err != nil {
+			if err == db.ErrNotExistKey {
+				return nil
 			} else {
-				goto again
+				return err
 			}
 		}
-	}()
+		prunedHeight = util.BytesToUint32(value)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
 
-	return &Store{
-		db:         db,
-		ticker:     ticker,
-		version:    version,
-		accounter:  act,
-		transactor: tran,
-		eventer:    evt,
-		SeqMap:     map[common.Address]uint64{},
-	}, nil
+	st := &Store{
+		db:           kv,
+		version:      version,
+		accounter:    act,
+		transactor:   tran,
+		eventer:      evt,
+		SeqMap:       map[common.Address]uint64{},
+		prunedHeight: prunedHeight,
+	}
+	if formatVersion < storeFormatVersion {
+		if err := st.migrate(formatVersion); err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
 }
 
 // Close terminate and clean store
@@ -104,9 +148,7 @@ func (st *Store) Close() {
 
 	st.isClose = true
 	st.db.Close()
-	st.ticker.Stop()
 	st.db = nil
-	st.ticker = nil
 }
 
 // CreateHeader returns a header that implements the chain header interface
@@ -184,16 +226,8 @@ func (st *Store) Hash(height uint32) (hash.Hash256, error) {
 	}
 
 	var h hash.Hash256
-	if err := st.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(toHeightHashKey(height))
-		if err != nil {
-			if err == badger.ErrKeyNotFound {
-				return db.ErrNotExistKey
-			} else {
-				return err
-			}
-		}
-		value, err := item.ValueCopy(nil)
+	if err := st.db.View(func(txn db.Txn) error {
+		value, err := txn.Get(toHeightHashKey(height))
 		if err != nil {
 			return err
 		}
@@ -225,16 +259,8 @@ func (st *Store) Header(height uint32) (chain.Header, error) {
 	}
 
 	var ch chain.Header
-	if err := st.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(toHeightHeaderKey(height))
-		if err != nil {
-			if err == badger.ErrKeyNotFound {
-				return db.ErrNotExistKey
-			} else {
-				return err
-			}
-		}
-		value, err := item.ValueCopy(nil)
+	if err := st.db.View(func(txn db.Txn) error {
+		value, err := txn.Get(toHeightHeaderKey(height))
 		if err != nil {
 			return err
 		}
@@ -260,6 +286,9 @@ func (st *Store) Data(height uint32) (*chain.Data, error) {
 	if height < 1 {
 		return nil, db.ErrNotExistKey
 	}
+	if height <= st.PrunedHeight() {
+		return nil, ErrPrunedData
+	}
 	if st.cache.cached {
 		if st.cache.height == height {
 			return st.cache.heightData, nil
@@ -267,16 +296,8 @@ func (st *Store) Data(height uint32) (*chain.Data, error) {
 	}
 
 	var cd *chain.Data
-	if err := st.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(toHeightDataKey(height))
-		if err != nil {
-			if err == badger.ErrKeyNotFound {
-				return db.ErrNotExistKey
-			} else {
-				return err
-			}
-		}
-		value, err := item.ValueCopy(nil)
+	if err := st.db.View(func(txn db.Txn) error {
+		value, err := txn.Get(toHeightDataKey(height))
 		if err != nil {
 			return err
 		}
@@ -307,16 +328,8 @@ func (st *Store) Height() uint32 {
 	}
 
 	var height uint32
-	st.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte("height"))
-		if err != nil {
-			if err == badger.ErrKeyNotFound {
-				return db.ErrNotExistKey
-			} else {
-				return err
-			}
-		}
-		value, err := item.ValueCopy(nil)
+	st.db.View(func(txn db.Txn) error {
+		value, err := txn.Get([]byte("height"))
 		if err != nil {
 			return err
 		}
@@ -335,15 +348,8 @@ func (st *Store) Accounts() ([]account.Account, error) {
 	}
 
 	list := []account.Account{}
-	if err := st.db.View(func(txn *badger.Txn) error {
-		it := txn.NewIterator(badger.DefaultIteratorOptions)
-		defer it.Close()
-		for it.Seek(tagAccount); it.ValidForPrefix(tagAccount); it.Next() {
-			item := it.Item()
-			value, err := item.ValueCopy(nil)
-			if err != nil {
-				return err
-			}
+	if err := st.db.View(func(txn db.Txn) error {
+		return txn.Iterate(tagAccount, tagAccount, func(key []byte, value []byte) error {
 			acc, err := st.accounter.NewByType(account.Type(value[0]))
 			if err != nil {
 				return err
@@ -352,8 +358,8 @@ func (st *Store) Accounts() ([]account.Account, error) {
 				return err
 			}
 			list = append(list, acc)
-		}
-		return nil
+			return nil
+		})
 	}); err != nil {
 		return nil, err
 	}
@@ -375,12 +381,8 @@ func (st *Store) Seq(addr common.Address) uint64 {
 		return seq
 	} else {
 		var seq uint64
-		if err := st.db.View(func(txn *badger.Txn) error {
-			item, err := txn.Get(toAccountSeqKey(addr))
-			if err != nil {
-				return err
-			}
-			value, err := item.ValueCopy(nil)
+		if err := st.db.View(func(txn db.Txn) error {
+			value, err := txn.Get(toAccountSeqKey(addr))
 			if err != nil {
 				return err
 			}
@@ -403,24 +405,17 @@ func (st *Store) LockedBalances(addr common.Address) ([]*data.LockedBalance, err
 	}
 
 	list := []*data.LockedBalance{}
-	if err := st.db.View(func(txn *badger.Txn) error {
-		it := txn.NewIterator(badger.DefaultIteratorOptions)
-		defer it.Close()
+	if err := st.db.View(func(txn db.Txn) error {
 		prefix := toLockedBalancePrefix(addr)
-		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-			item := it.Item()
-			value, err := item.ValueCopy(nil)
-			if err != nil {
-				return err
-			}
-			Address, UnlockHeight := fromLockedBalanceKey(item.Key())
+		return txn.Iterate(prefix, prefix, func(key []byte, value []byte) error {
+			Address, UnlockHeight := fromLockedBalanceKey(key)
 			list = append(list, &data.LockedBalance{
 				Address:      Address,
 				Amount:       amount.NewAmountFromBytes(value),
 				UnlockHeight: UnlockHeight,
 			})
-		}
-		return nil
+			return nil
+		})
 	}); err != nil {
 		return nil, err
 	}
@@ -436,24 +431,17 @@ func (st *Store) LockedBalancesByHeight(Height uint32) ([]*data.LockedBalance, e
 	}
 
 	list := []*data.LockedBalance{}
-	if err := st.db.View(func(txn *badger.Txn) error {
-		it := txn.NewIterator(badger.DefaultIteratorOptions)
-		defer it.Close()
+	if err := st.db.View(func(txn db.Txn) error {
 		prefix := toLockedBalanceHeightPrefix(Height)
-		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-			item := it.Item()
-			value, err := item.ValueCopy(nil)
-			if err != nil {
-				return err
-			}
-			Address, UnlockHeight := fromLockedBalanceHeightKey(item.Key())
+		return txn.Iterate(prefix, prefix, func(key []byte, value []byte) error {
+			Address, UnlockHeight := fromLockedBalanceHeightKey(key)
 			list = append(list, &data.LockedBalance{
 				Address:      Address,
 				Amount:       amount.NewAmountFromBytes(value),
 				UnlockHeight: UnlockHeight,
 			})
-		}
-		return nil
+			return nil
+		})
 	}); err != nil {
 		return nil, err
 	}
@@ -469,16 +457,8 @@ func (st *Store) Account(addr common.Address) (account.Account, error) {
 	}
 
 	var acc account.Account
-	if err := st.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(toAccountKey(addr))
-		if err != nil {
-			if err == badger.ErrKeyNotFound {
-				return db.ErrNotExistKey
-			} else {
-				return err
-			}
-		}
-		value, err := item.ValueCopy(nil)
+	if err := st.db.View(func(txn db.Txn) error {
+		value, err := txn.Get(toAccountKey(addr))
 		if err != nil {
 			return err
 		}
@@ -509,16 +489,8 @@ func (st *Store) AddressByName(Name string) (common.Address, error) {
 	}
 
 	var addr common.Address
-	if err := st.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(toAccountNameKey(Name))
-		if err != nil {
-			if err == badger.ErrKeyNotFound {
-				return db.ErrNotExistKey
-			} else {
-				return err
-			}
-		}
-		value, err := item.ValueCopy(nil)
+	if err := st.db.View(func(txn db.Txn) error {
+		value, err := txn.Get(toAccountNameKey(Name))
 		if err != nil {
 			return err
 		}
@@ -545,16 +517,11 @@ func (st *Store) IsExistAccount(addr common.Address) (bool, error) {
 	}
 
 	var isExist bool
-	if err := st.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(toAccountKey(addr))
-		if err != nil {
-			if err == badger.ErrKeyNotFound {
-				return db.ErrNotExistKey
-			} else {
-				return err
-			}
+	if err := st.db.View(func(txn db.Txn) error {
+		if _, err := txn.Get(toAccountKey(addr)); err != nil {
+			return err
 		}
-		isExist = !item.IsDeletedOrExpired()
+		isExist = true
 		return nil
 	}); err != nil {
 		if err == db.ErrNotExistKey {
@@ -575,16 +542,11 @@ func (st *Store) IsExistAccountName(Name string) (bool, error) {
 	}
 
 	var isExist bool
-	if err := st.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(toAccountNameKey(Name))
-		if err != nil {
-			if err == badger.ErrKeyNotFound {
-				return db.ErrNotExistKey
-			} else {
-				return err
-			}
+	if err := st.db.View(func(txn db.Txn) error {
+		if _, err := txn.Get(toAccountNameKey(Name)); err != nil {
+			return err
 		}
-		isExist = !item.IsDeletedOrExpired()
+		isExist = true
 		return nil
 	}); err != nil {
 		if err == db.ErrNotExistKey {
@@ -605,19 +567,15 @@ func (st *Store) AccountDataKeys(addr common.Address, Prefix []byte) ([][]byte,
 	}
 
 	list := [][]byte{}
-	if err := st.db.View(func(txn *badger.Txn) error {
-		it := txn.NewIterator(badger.DefaultIteratorOptions)
-		defer it.Close()
+	if err := st.db.View(func(txn db.Txn) error {
 		pre := toAccountDataKey(string(addr[:]))
 		if len(Prefix) > 0 {
 			pre = append(pre, Prefix...)
 		}
-		for it.Seek(pre); it.ValidForPrefix(pre); it.Next() {
-			item := it.Item()
-			key := item.Key()
+		return txn.Iterate(pre, pre, func(key []byte, value []byte) error {
 			list = append(list, key[len(pre):])
-		}
-		return nil
+			return nil
+		})
 	}); err != nil {
 		return nil, err
 	}
@@ -634,12 +592,8 @@ func (st *Store) AccountData(addr common.Address, name []byte) []byte {
 
 	key := string(addr[:]) + string(name)
 	var data []byte
-	if err := st.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(toAccountDataKey(key))
-		if err != nil {
-			return err
-		}
-		value, err := item.ValueCopy(nil)
+	if err := st.db.View(func(txn db.Txn) error {
+		value, err := txn.Get(toAccountDataKey(key))
 		if err != nil {
 			return err
 		}
@@ -660,25 +614,18 @@ func (st *Store) UTXOs() ([]*transaction.UTXO, error) {
 	}
 
 	list := []*transaction.UTXO{}
-	if err := st.db.View(func(txn *badger.Txn) error {
-		it := txn.NewIterator(badger.DefaultIteratorOptions)
-		defer it.Close()
-		for it.Seek(tagUTXO); it.ValidForPrefix(tagUTXO); it.Next() {
-			item := it.Item()
-			value, err := item.ValueCopy(nil)
-			if err != nil {
-				return err
-			}
+	if err := st.db.View(func(txn db.Txn) error {
+		return txn.Iterate(tagUTXO, tagUTXO, func(key []byte, value []byte) error {
 			utxo := &transaction.UTXO{
-				TxIn:  transaction.NewTxIn(fromUTXOKey(item.Key())),
+				TxIn:  transaction.NewTxIn(fromUTXOKey(key)),
 				TxOut: transaction.NewTxOut(),
 			}
 			if _, err := utxo.TxOut.ReadFrom(bytes.NewReader(value)); err != nil {
 				return err
 			}
 			list = append(list, utxo)
-		}
-		return nil
+			return nil
+		})
 	}); err != nil {
 		return nil, err
 	}
@@ -694,16 +641,11 @@ func (st *Store) IsExistUTXO(id uint64) (bool, error) {
 	}
 
 	var isExist bool
-	if err := st.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(toUTXOKey(id))
-		if err != nil {
-			if err == badger.ErrKeyNotFound {
-				return db.ErrNotExistKey
-			} else {
-				return err
-			}
+	if err := st.db.View(func(txn db.Txn) error {
+		if _, err := txn.Get(toUTXOKey(id)); err != nil {
+			return err
 		}
-		isExist = !item.IsDeletedOrExpired()
+		isExist = true
 		return nil
 	}); err != nil {
 		if err == db.ErrNotExistKey {
@@ -724,19 +666,15 @@ func (st *Store) UTXO(id uint64) (*transaction.UTXO, error) {
 	}
 
 	var utxo *transaction.UTXO
-	if err := st.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(toUTXOKey(id))
+	if err := st.db.View(func(txn db.Txn) error {
+		value, err := txn.Get(toUTXOKey(id))
 		if err != nil {
-			if err == badger.ErrKeyNotFound {
+			if err == db.ErrNotExistKey {
 				return data.ErrNotExistUTXO
 			} else {
 				return err
 			}
 		}
-		value, err := item.ValueCopy(nil)
-		if err != nil {
-			return err
-		}
 		utxo = &transaction.UTXO{
 			TxIn:  transaction.NewTxIn(id),
 			TxOut: transaction.NewTxOut(),
@@ -760,16 +698,29 @@ func (st *Store) CustomData(key string) []byte {
 	}
 
 	var bs []byte
-	st.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(toCustomData(key))
+	st.db.View(func(txn db.Txn) error {
+		value, err := txn.Get(toCustomData(key))
 		if err != nil {
-			if err == badger.ErrKeyNotFound {
-				return db.ErrNotExistKey
-			} else {
-				return err
-			}
+			return err
 		}
-		value, err := item.ValueCopy(nil)
+		bs = value
+		return nil
+	})
+	return bs
+}
+
+// StateCustomData returns the custom data that is stored with blocks by the key from the store
+// It is committed to the state root, so it should be the same for the same chain
+func (st *Store) StateCustomData(key string) []byte {
+	st.closeLock.RLock()
+	defer st.closeLock.RUnlock()
+	if st.isClose {
+		return nil
+	}
+
+	var bs []byte
+	st.db.View(func(txn db.Txn) error {
+		value, err := txn.Get(toStateCustomDataKey(key))
 		if err != nil {
 			return err
 		}
@@ -787,7 +738,7 @@ func (st *Store) SetCustomData(key string, value []byte) error {
 		return ErrStoreClosed
 	}
 
-	return st.db.Update(func(txn *badger.Txn) error {
+	return st.db.Update(func(txn db.Txn) error {
 		if err := txn.Set(toCustomData(key), value); err != nil {
 			return err
 		}
@@ -803,7 +754,7 @@ func (st *Store) DeleteCustomData(key string) error {
 		return ErrStoreClosed
 	}
 
-	return st.db.Update(func(txn *badger.Txn) error {
+	return st.db.Update(func(txn db.Txn) error {
 		if err := txn.Delete(toCustomData(key)); err != nil {
 			return err
 		}
@@ -820,17 +771,10 @@ func (st *Store) Events(From uint32, To uint32) ([]event.Event, error) {
 	}
 
 	list := []event.Event{}
-	if err := st.db.View(func(txn *badger.Txn) error {
-		it := txn.NewIterator(badger.DefaultIteratorOptions)
-		defer it.Close()
+	if err := st.db.View(func(txn db.Txn) error {
 		tagBegin := toEventKey(event.MarshalID(common.NewCoordinate(From, 0), 0))
 		tagEnd := toEventKey(event.MarshalID(common.NewCoordinate(To, 65535), 65535))
-		for it.Seek(tagBegin); it.ValidForPrefix(tagEnd); it.Next() {
-			item := it.Item()
-			value, err := item.ValueCopy(nil)
-			if err != nil {
-				return err
-			}
+		return txn.Iterate(tagBegin, tagEnd, func(key []byte, value []byte) error {
 			acc, err := st.eventer.NewByType(event.Type(util.BytesToUint64(value[:8])))
 			if err != nil {
 				return err
@@ -839,8 +783,8 @@ func (st *Store) Events(From uint32, To uint32) ([]event.Event, error) {
 				return err
 			}
 			list = append(list, acc)
-		}
-		return nil
+			return nil
+		})
 	}); err != nil {
 		return nil, err
 	}
@@ -858,7 +802,7 @@ func (st *Store) StoreGenesis(genHash hash.Hash256, ctd *data.ContextData, custo
 	if st.Height() > 0 {
 		return chain.ErrAlreadyGenesised
 	}
-	if err := st.db.Update(func(txn *badger.Txn) error {
+	if err := st.db.Update(func(txn db.Txn) error {
 		{
 			if err := txn.Set(toHeightHashKey(0), genHash[:]); err != nil {
 				return err
@@ -871,14 +815,18 @@ func (st *Store) StoreGenesis(genHash hash.Hash256, ctd *data.ContextData, custo
 				return err
 			}
 		}
-		if err := applyContextData(txn, ctd); err != nil {
+		ut := newUndoTxn(txn)
+		if err := applyContextData(ut, ctd); err != nil {
 			return err
 		}
 		for k, v := range customHash {
-			if err := txn.Set(toCustomData(k), v); err != nil {
+			if err := ut.Set(toStateCustomDataKey(k), v); err != nil {
 				return err
 			}
 		}
+		if err := updateStateTree(ut, hash.Hash256{}, 0); err != nil {
+			return err
+		}
 		return nil
 	}); err != nil {
 		return err
@@ -899,13 +847,14 @@ func (st *Store) StoreData(cd *chain.Data, ctd *data.ContextData, customHash map
 	}
 
 	DataHash := cd.Header.Hash()
-	if err := st.db.Update(func(txn *badger.Txn) error {
+	if err := st.db.Update(func(txn db.Txn) error {
+		ut := newUndoTxn(txn)
 		{
 			var buffer bytes.Buffer
 			if _, err := cd.WriteTo(&buffer); err != nil {
 				return err
 			}
-			if err := txn.Set(toHeightDataKey(cd.Header.Height()), buffer.Bytes()); err != nil {
+			if err := ut.Set(toHeightDataKey(cd.Header.Height()), buffer.Bytes()); err != nil {
 				return err
 			}
 		}
@@ -914,29 +863,50 @@ func (st *Store) StoreData(cd *chain.Data, ctd *data.ContextData, customHash map
 			if _, err := cd.Header.WriteTo(&buffer); err != nil {
 				return err
 			}
-			if err := txn.Set(toHeightHeaderKey(cd.Header.Height()), buffer.Bytes()); err != nil {
+			if err := ut.Set(toHeightHeaderKey(cd.Header.Height()), buffer.Bytes()); err != nil {
 				return err
 			}
 		}
 		{
-			if err := txn.Set(toHeightHashKey(cd.Header.Height()), DataHash[:]); err != nil {
+			if err := ut.Set(toHeightHashKey(cd.Header.Height()), DataHash[:]); err != nil {
 				return err
 			}
 			bsHeight := util.Uint32ToBytes(cd.Header.Height())
-			if err := txn.Set(toHashHeightKey(DataHash), bsHeight); err != nil {
+			if err := ut.Set(toHashHeightKey(DataHash), bsHeight); err != nil {
 				return err
 			}
-			if err := txn.Set([]byte("height"), bsHeight); err != nil {
+			if err := ut.Set([]byte("height"), bsHeight); err != nil {
 				return err
 			}
 		}
-		if err := applyContextData(txn, ctd); err != nil {
+		if err := applyContextData(ut, ctd); err != nil {
 			return err
 		}
 		for k, v := range customHash {
-			if err := txn.Set(toCustomData(k), v); err != nil {
+			if err := ut.Set(toStateCustomDataKey(k), v); err != nil {
+				return err
+			}
+		}
+		if root, err := stateRootHash(txn, cd.Header.Height()-1); err != nil {
+			// only the genesis can have the empty state tree
+			if err != db.ErrNotExistKey || cd.Header.Height() != 1 {
+				return err
+			}
+			if err := updateStateTree(ut, hash.Hash256{}, cd.Header.Height()); err != nil {
 				return err
 			}
+		} else if err := updateStateTree(ut, root, cd.Header.Height()); err != nil {
+			return err
+		}
+		if st.isIndexing {
+			if err := indexTransactions(ut, cd.Header.Height(), cd.Body.(*block.Body), ctd); err != nil {
+				return err
+			}
+		}
+		if bs, err := ut.Bytes(); err != nil {
+			return err
+		} else if err := txn.Set(toHeightUndoKey(cd.Header.Height()), bs); err != nil {
+			return err
 		}
 		return nil
 	}); err != nil {
@@ -951,10 +921,172 @@ func (st *Store) StoreData(cd *chain.Data, ctd *data.ContextData, customHash map
 	st.cache.heightHash = DataHash
 	st.cache.heightData = cd
 	st.cache.cached = true
+
+	if st.keepBlocks > 0 && cd.Header.Height() > st.keepBlocks {
+		// the stored block is not affected by the failed pruning and it is retried by the next block
+		st.prune(cd.Header.Height() - st.keepBlocks)
+	}
 	return nil
 }
 
-func applyContextData(txn *badger.Txn, ctd *data.ContextData) error {
+// prune deletes bodies, undo journals and stale state of heights up to the target after the block is stored
+// Each height is pruned in its own transaction to bound the size of the transaction
+// Pruned keys are not recorded to the undo journal because they are not recovered by the rewind
+func (st *Store) prune(target uint32) error {
+	LastPrunedHeight := st.PrunedHeight()
+	if target > LastPrunedHeight+maxPruneHeightsPerBlock {
+		target = LastPrunedHeight + maxPruneHeightsPerBlock
+	}
+	PrunedHeight := LastPrunedHeight
+	defer func() {
+		if PrunedHeight/compactPrunedHeights > LastPrunedHeight/compactPrunedHeights {
+			go st.compactInBackground()
+		}
+	}()
+	for h := LastPrunedHeight + 1; h <= target; h++ {
+		if err := st.db.Update(func(txn db.Txn) error {
+			if err := txn.Delete(toHeightDataKey(h)); err != nil {
+				return err
+			}
+			if err := txn.Delete(toHeightUndoKey(h)); err != nil {
+				return err
+			}
+			if err := pruneStateNodes(txn, h); err != nil {
+				return err
+			}
+			// the state root is kept during the state root lag because following blocks are validated by it
+			if h > st.stateRootLag {
+				if err := txn.Delete(toHeightStateRootKey(h - st.stateRootLag)); err != nil {
+					return err
+				}
+			}
+			if err := txn.Set([]byte("prunedheight"), util.Uint32ToBytes(h)); err != nil {
+				return err
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		st.Lock()
+		st.prunedHeight = h
+		st.Unlock()
+		PrunedHeight = h
+	}
+	return nil
+}
+
+// Rewind rolls the store back to the given height using the undo journals of the stored blocks
+func (st *Store) Rewind(height uint32) error {
+	st.closeLock.RLock()
+	defer st.closeLock.RUnlock()
+	if st.isClose {
+		return ErrStoreClosed
+	}
+
+	st.Lock()
+	defer st.Unlock()
+
+	current := st.Height()
+	if height > current {
+		return ErrInvalidRewindHeight
+	}
+	if height < st.prunedHeight {
+		return ErrPrunedData
+	}
+	// undo journals of all heights are checked before the rewind to not stop at the middle of it by the missing journal
+	if err := st.db.View(func(txn db.Txn) error {
+		for h := current; h > height; h-- {
+			if _, err := txn.Get(toHeightUndoKey(h)); err != nil {
+				if err == db.ErrNotExistKey {
+					return ErrNotExistUndoData
+				} else {
+					return err
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	// each height is rolled back in its own transaction to bound the size of the transaction
+	// so the failed rewind leaves the store at the last rolled back height
+	defer st.resetCache()
+	for h := current; h > height; h-- {
+		if err := st.db.Update(func(txn db.Txn) error {
+			value, err := txn.Get(toHeightUndoKey(h))
+			if err != nil {
+				if err == db.ErrNotExistKey {
+					return ErrNotExistUndoData
+				} else {
+					return err
+				}
+			}
+			entries, err := readUndoEntries(value)
+			if err != nil {
+				return err
+			}
+			for i := len(entries) - 1; i >= 0; i-- {
+				e := entries[i]
+				if e.IsExist {
+					if err := txn.Set(e.Key, e.Value); err != nil {
+						return err
+					}
+				} else {
+					if err := txn.Delete(e.Key); err != nil {
+						return err
+					}
+				}
+			}
+			if err := txn.Delete(toHeightUndoKey(h)); err != nil {
+				return err
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrunedHeight returns the height that block bodies below and equal to it are pruned
+func (st *Store) PrunedHeight() uint32 {
+	st.Lock()
+	defer st.Unlock()
+
+	return st.prunedHeight
+}
+
+// Compact reclaims the space of the deleted and the pruned values from the value log
+func (st *Store) Compact() error {
+	st.closeLock.RLock()
+	defer st.closeLock.RUnlock()
+	if st.isClose {
+		return ErrStoreClosed
+	}
+
+	return st.db.Compact()
+}
+
+// compactInBackground compacts the db if another compaction is not running
+// The failed compaction is retried by the next trigger
+func (st *Store) compactInBackground() {
+	if !atomic.CompareAndSwapInt32(&st.isCompacting, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&st.isCompacting, 0)
+
+	st.Compact()
+}
+
+func (st *Store) resetCache() {
+	st.SeqMapLock.Lock()
+	st.SeqMap = map[common.Address]uint64{}
+	st.SeqMapLock.Unlock()
+	st.cache.cached = false
+	st.cache.heightData = nil
+}
+
+func applyContextData(txn *undoTxn, ctd *data.ContextData) error {
 	for k, v := range ctd.SeqMap {
 		if err := txn.Set(toAccountSeqKey(k), util.Uint64ToBytes(v)); err != nil {
 			return err
@@ -962,17 +1094,13 @@ func applyContextData(txn *badger.Txn, ctd *data.ContextData) error {
 	}
 	for _, v := range ctd.LockedBalances {
 		var AmountSum *amount.Amount
-		item, err := txn.Get(toLockedBalanceKey(v.Address, v.UnlockHeight))
+		value, err := txn.Get(toLockedBalanceKey(v.Address, v.UnlockHeight))
 		if err != nil {
-			if err != badger.ErrKeyNotFound {
+			if err != db.ErrNotExistKey {
 				return err
 			}
 			AmountSum = amount.NewCoinAmount(0, 0)
 		} else {
-			value, err := item.ValueCopy(nil)
-			if err != nil {
-				return err
-			}
 			AmountSum = amount.NewAmountFromBytes(value)
 		}
 		if err := txn.Set(toLockedBalanceKey(v.Address, v.UnlockHeight), AmountSum.Add(v.Amount).Bytes()); err != nil {
@@ -990,7 +1118,8 @@ func applyContextData(txn *badger.Txn, ctd *data.ContextData) error {
 			return err
 		}
 	}
-	for k, v := range ctd.AccountMap {
+	// the account that is created and updated in the same block is in both maps and the updated one is in the account map
+	for k, v := range ctd.CreatedAccountMap {
 		var buffer bytes.Buffer
 		buffer.WriteByte(byte(v.Type()))
 		if _, err := v.WriteTo(&buffer); err != nil {
@@ -1003,7 +1132,7 @@ func applyContextData(txn *badger.Txn, ctd *data.ContextData) error {
 			return err
 		}
 	}
-	for k, v := range ctd.CreatedAccountMap {
+	for k, v := range ctd.AccountMap {
 		var buffer bytes.Buffer
 		buffer.WriteByte(byte(v.Type()))
 		if _, err := v.WriteTo(&buffer); err != nil {
@@ -1012,20 +1141,37 @@ func applyContextData(txn *badger.Txn, ctd *data.ContextData) error {
 		if err := txn.Set(toAccountKey(k), buffer.Bytes()); err != nil {
 			return err
 		}
+		if err := txn.Set(toAccountNameKey(v.Name()), k[:]); err != nil {
+			return err
+		}
 	}
-	for k := range ctd.DeletedAccountMap {
+	for k, v := range ctd.DeletedAccountMap {
 		if err := txn.Delete(toAccountKey(k)); err != nil {
 			return err
 		}
+		// the name can be used by the account that is created after deleting it
+		if value, err := txn.Get(toAccountNameKey(v.Name())); err != nil {
+			if err != db.ErrNotExistKey {
+				return err
+			}
+		} else if bytes.Equal(value, k[:]) {
+			if err := txn.Delete(toAccountNameKey(v.Name())); err != nil {
+				return err
+			}
+		}
 		if err := txn.Delete(toAccountBalanceKey(k)); err != nil {
 			return err
 		}
-		it := txn.NewIterator(badger.DefaultIteratorOptions)
-		defer it.Close()
 		prefix := toAccountDataKey(string(k[:]))
-		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-			item := it.Item()
-			if err := txn.Delete(item.Key()); err != nil {
+		keys := [][]byte{}
+		if err := txn.Iterate(prefix, prefix, func(key []byte, value []byte) error {
+			keys = append(keys, key)
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := txn.Delete(key); err != nil {
 				return err
 			}
 		}
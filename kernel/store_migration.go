@@ -0,0 +1,260 @@
+package kernel
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/common/util"
+	"github.com/fletaio/core/account"
+	"github.com/fletaio/core/db"
+	"github.com/fletaio/core/statetree"
+)
+
+// blockCustomDataKeys are keys of the custom data that the kernel stores with blocks
+// The store of the format version 1 keeps them with the local custom data, so they are moved to the state custom data by the migration
+var blockCustomDataKeys = []string{"consensus", "reward"}
+
+// migrate upgrades the layout of the store of the format version to the current one
+// Each step can be executed again, so the migration that is stopped at the middle of it is continued by the next start
+// The store of the format version 1 has no undo journal, so it cannot be rewound before the migrated height
+func (st *Store) migrate(version uint32) error {
+	if version < 2 {
+		if err := st.migrateLockedBalanceHeightIndex(); err != nil {
+			return err
+		}
+		if err := st.migrateStateCustomData(); err != nil {
+			return err
+		}
+		if err := st.migrateAccountNames(); err != nil {
+			return err
+		}
+		if err := st.migrateStateTree(); err != nil {
+			return err
+		}
+	}
+	return st.db.Update(func(txn db.Txn) error {
+		return txn.Set([]byte("storeversion"), util.Uint32ToBytes(storeFormatVersion))
+	})
+}
+
+// toPreviousLockedBalanceHeightKey returns the locked balance height key of the format version 1 that shares the tag of the locked balance key
+func toPreviousLockedBalanceHeightKey(UnlockHeight uint32, Address common.Address) []byte {
+	bs := make([]byte, 6+common.AddressSize)
+	copy(bs, tagLockedBalance)
+	binary.LittleEndian.PutUint32(bs[2:], UnlockHeight)
+	copy(bs[6:], Address[:])
+	return bs
+}
+
+// migrateLockedBalanceHeightIndex moves the locked balance height index out of the locked balance keys
+// Both keys have the same length, so the locked balance key is the one that has the index key of the swapped address and height
+// The index of the new tag is stored before deleting previous keys to find locked balance keys again when it is stopped
+func (st *Store) migrateLockedBalanceHeightIndex() error {
+	keyMap := map[string][]byte{}
+	if err := st.db.View(func(txn db.Txn) error {
+		return txn.Iterate(tagLockedBalance, tagLockedBalance, func(key []byte, value []byte) error {
+			keyMap[string(key)] = value
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+
+	records := [][]byte{}
+	removed := [][]byte{}
+	if err := st.db.View(func(txn db.Txn) error {
+		for k, v := range keyMap {
+			key := []byte(k)
+			if len(key) != 6+common.AddressSize {
+				continue
+			}
+			addr, UnlockHeight := fromLockedBalanceKey(key)
+			if _, has := keyMap[string(toPreviousLockedBalanceHeightKey(UnlockHeight, addr))]; has {
+				records = append(records, toLockedBalanceHeightKey(UnlockHeight, addr), v)
+			} else if _, err := txn.Get(toLockedBalanceHeightKey(UnlockHeight, addr)); err == nil {
+				records = append(records, toLockedBalanceHeightKey(UnlockHeight, addr), v)
+			} else if err != db.ErrNotExistKey {
+				return err
+			} else {
+				removed = append(removed, key)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for len(records) > 0 {
+		batch := records
+		if len(batch) > importBatchSize*2 {
+			batch = batch[:importBatchSize*2]
+		}
+		if err := st.db.Update(func(txn db.Txn) error {
+			for i := 0; i < len(batch); i += 2 {
+				if err := txn.Set(batch[i], batch[i+1]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		records = records[len(batch):]
+	}
+	return st.deleteKeys(removed)
+}
+
+// migrateStateCustomData moves the custom data of blocks to the state custom data
+func (st *Store) migrateStateCustomData() error {
+	return st.db.Update(func(txn db.Txn) error {
+		for _, k := range blockCustomDataKeys {
+			value, err := txn.Get(toCustomData(k))
+			if err != nil {
+				if err == db.ErrNotExistKey {
+					continue
+				} else {
+					return err
+				}
+			}
+			if err := txn.Set(toStateCustomDataKey(k), value); err != nil {
+				return err
+			}
+			if err := txn.Delete(toCustomData(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// migrateAccountNames stores names of all accounts and deletes names of deleted accounts
+func (st *Store) migrateAccountNames() error {
+	if err := st.rebuildIndex(tagAccount, func(key []byte, value []byte) ([]byte, []byte, error) {
+		if len(value) == 0 {
+			return nil, nil, ErrInvalidStoredAccount
+		}
+		acc, err := st.accounter.NewByType(account.Type(value[0]))
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := acc.ReadFrom(bytes.NewReader(value[1:])); err != nil {
+			return nil, nil, err
+		}
+		addr := acc.Address()
+		return toAccountNameKey(acc.Name()), addr[:], nil
+	}); err != nil {
+		return err
+	}
+
+	removed := [][]byte{}
+	if err := st.db.View(func(txn db.Txn) error {
+		return txn.Iterate(tagAccountName, tagAccountName, func(key []byte, value []byte) error {
+			var addr common.Address
+			if len(value) != len(addr) {
+				removed = append(removed, key)
+				return nil
+			}
+			copy(addr[:], value)
+			if _, err := txn.Get(toAccountKey(addr)); err != nil {
+				if err != db.ErrNotExistKey {
+					return err
+				}
+				removed = append(removed, key)
+			}
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+	return st.deleteKeys(removed)
+}
+
+// migrateStateTree builds the state tree of the current height from state keys by the batch
+// Nodes of the tree of the previous batch are deleted because the store of the format version 1 has no other tree
+func (st *Store) migrateStateTree() error {
+	var height uint32
+	if err := st.db.View(func(txn db.Txn) error {
+		value, err := txn.Get([]byte("height"))
+		if err != nil {
+			return err
+		}
+		height = util.BytesToUint32(value)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	var root hash.Hash256
+	for _, prefix := range snapshotPrefixes {
+		begin := prefix
+		for {
+			kv := map[string][]byte{}
+			if err := st.db.View(func(txn db.Txn) error {
+				return txn.Iterate(begin, prefix, func(key []byte, value []byte) error {
+					kv[string(key)] = value
+					if len(kv) >= importBatchSize {
+						// the next batch begins from the key right after the last one
+						begin = append(key, 0)
+						return db.ErrStopIteration
+					}
+					return nil
+				})
+			}); err != nil {
+				return err
+			}
+			if len(kv) == 0 {
+				break
+			}
+			if err := st.db.Update(func(txn db.Txn) error {
+				NewRoot, nodes, stale, err := statetree.Update(&stateNodeStore{txn: txn}, root, kv)
+				if err != nil {
+					return err
+				}
+				for _, h := range stale {
+					if err := txn.Delete(toStateNodeKey(h)); err != nil {
+						return err
+					}
+				}
+				for h, bs := range nodes {
+					if err := txn.Set(toStateNodeKey(h), bs); err != nil {
+						return err
+					}
+				}
+				root = NewRoot
+				return nil
+			}); err != nil {
+				return err
+			}
+			if len(kv) < importBatchSize {
+				break
+			}
+		}
+	}
+	return st.db.Update(func(txn db.Txn) error {
+		return txn.Set(toHeightStateRootKey(height), root[:])
+	})
+}
+
+// deleteKeys deletes keys by the batch
+func (st *Store) deleteKeys(keys [][]byte) error {
+	for len(keys) > 0 {
+		batch := keys
+		if len(batch) > importBatchSize {
+			batch = batch[:importBatchSize]
+		}
+		if err := st.db.Update(func(txn db.Txn) error {
+			for _, key := range batch {
+				if err := txn.Delete(key); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		keys = keys[len(batch):]
+	}
+	return nil
+}
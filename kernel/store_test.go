@@ -0,0 +1,360 @@
+package kernel
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/common/util"
+	"github.com/fletaio/core/amount"
+	"github.com/fletaio/core/block"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/core/db"
+	"github.com/fletaio/core/statetree"
+	"github.com/fletaio/core/transaction"
+	"github.com/fletaio/framework/chain"
+)
+
+func Test_StoreWithMemoryDB(t *testing.T) {
+	testStoreRewind(t, db.NewMemoryDB())
+}
+
+func Test_StoreWithBadgerDB(t *testing.T) {
+	path, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	bdb, err := db.NewBadgerDB(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStoreRewind(t, bdb)
+}
+
+func testStoreRewind(t *testing.T, kv db.DB) {
+	coord := common.NewCoordinate(0, 0)
+	st, err := NewStoreWithDB(kv, 1, data.NewAccounter(coord), data.NewTransactor(coord), data.NewEventer(coord))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	var addr common.Address
+	addr[0] = 1
+	if err := st.StoreGenesis(hash.Hash([]byte("genesis")), data.NewContextData(nil, nil), map[string][]byte{"consensus": []byte{0}}); err != nil {
+		t.Fatal(err)
+	}
+	storeData := func(height uint32) hash.Hash256 {
+		ctd := data.NewContextData(nil, nil)
+		ctd.SeqMap[addr] = uint64(height)
+		cd := &chain.Data{
+			Header: &block.Header{
+				Base: chain.Base{
+					Height_:    height,
+					Timestamp_: uint64(height),
+				},
+			},
+			Body: &block.Body{
+				Transactions:          []transaction.Transaction{},
+				TransactionSignatures: [][]common.Signature{},
+				Tran:                  st.Transactor(),
+			},
+		}
+		if err := st.StoreData(cd, ctd, map[string][]byte{"consensus": []byte{byte(height)}}); err != nil {
+			t.Fatal(err)
+		}
+		root, err := st.StateRootHash(height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return root
+	}
+
+	roots := map[uint32]hash.Hash256{}
+	for h := uint32(1); h <= 5; h++ {
+		roots[h] = storeData(h)
+	}
+
+	if err := st.Rewind(6); err != ErrInvalidRewindHeight {
+		t.Fatal("rewind to the future height is not rejected", err)
+	}
+	if err := st.Rewind(2); err != nil {
+		t.Fatal(err)
+	}
+	if st.Height() != 2 {
+		t.Fatal("invalid height after the rewind", st.Height())
+	}
+	if seq := st.Seq(addr); seq != 2 {
+		t.Fatal("invalid seq after the rewind", seq)
+	}
+	if bs := st.StateCustomData("consensus"); len(bs) != 1 || bs[0] != 2 {
+		t.Fatal("invalid custom data after the rewind", bs)
+	}
+	if _, err := st.StateRootHash(3); err != db.ErrNotExistKey {
+		t.Fatal("state root of the rewound height is remained", err)
+	}
+
+	value, proof, height, err := st.Prove(AccountSeqStateKey(addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height != 2 || !statetree.VerifyProof(roots[2], AccountSeqStateKey(addr), value, proof) {
+		t.Fatal("invalid proof after the rewind")
+	}
+
+	if root := storeData(3); !root.Equal(roots[3]) {
+		t.Fatal("state root is not same after storing the rewound height again")
+	}
+}
+
+func Test_StoreUndo(t *testing.T) {
+	coord := common.NewCoordinate(0, 0)
+	st, err := NewStoreWithDB(db.NewMemoryDB(), 1, data.NewAccounter(coord), data.NewTransactor(coord), data.NewEventer(coord))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	var addr common.Address
+	addr[0] = 1
+	if err := st.StoreGenesis(hash.Hash([]byte("genesis")), data.NewContextData(nil, nil), map[string][]byte{"consensus": []byte{0}}); err != nil {
+		t.Fatal(err)
+	}
+	for h := uint32(1); h <= 3; h++ {
+		ctd := data.NewContextData(nil, nil)
+		ctd.SeqMap[addr] = uint64(h)
+		ctd.AccountDataMap[string(addr[:])+"name"] = []byte{byte(h)}
+		ctd.LockedBalances = append(ctd.LockedBalances, &data.LockedBalance{
+			Address:      addr,
+			Amount:       amount.NewCoinAmount(uint64(h), 0),
+			UnlockHeight: 10,
+		})
+		if h == 2 {
+			ctd.CreatedUTXOMap[uint64(h)] = transaction.NewTxOut()
+		}
+		testStoreData(t, st, h, ctd)
+	}
+
+	// the undo journal keeps previous values of keys updated by the height and the absence of created keys
+	if err := st.db.View(func(txn db.Txn) error {
+		value, err := txn.Get(toHeightUndoKey(3))
+		if err != nil {
+			return err
+		}
+		entries, err := readUndoEntries(value)
+		if err != nil {
+			return err
+		}
+		entryMap := map[string]*undoEntry{}
+		for _, e := range entries {
+			entryMap[string(e.Key)] = e
+		}
+		if e, has := entryMap[string(toAccountSeqKey(addr))]; !has || !e.IsExist || util.BytesToUint64(e.Value) != 2 {
+			t.Error("invalid undo entry of the sequence", e)
+		}
+		if e, has := entryMap[string(toHeightDataKey(3))]; !has || e.IsExist {
+			t.Error("invalid undo entry of the block data", e)
+		}
+		if e, has := entryMap[string(toLockedBalanceKey(addr, 10))]; !has || !e.IsExist || !amount.NewAmountFromBytes(e.Value).Equal(amount.NewCoinAmount(3, 0)) {
+			t.Error("invalid undo entry of the locked balance", e)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the missing undo journal stops the rewind before rolling back any height
+	if err := st.db.Update(func(txn db.Txn) error {
+		return txn.Delete(toHeightUndoKey(2))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Rewind(1); err != ErrNotExistUndoData {
+		t.Fatal("rewind without the undo journal is not rejected", err)
+	}
+	if st.Height() != 3 {
+		t.Fatal("height is changed by the failed rewind", st.Height())
+	}
+
+	if err := st.Rewind(2); err != nil {
+		t.Fatal(err)
+	}
+	if bs := st.AccountData(addr, []byte("name")); len(bs) != 1 || bs[0] != 2 {
+		t.Fatal("invalid account data after the rewind", bs)
+	}
+	if list, err := st.LockedBalances(addr); err != nil {
+		t.Fatal(err)
+	} else if len(list) != 1 || !list[0].Amount.Equal(amount.NewCoinAmount(3, 0)) {
+		t.Fatal("invalid locked balances after the rewind", list)
+	}
+	if list, err := st.LockedBalancesByHeight(10); err != nil {
+		t.Fatal(err)
+	} else if len(list) != 1 || !list[0].Amount.Equal(amount.NewCoinAmount(3, 0)) {
+		t.Fatal("invalid locked balance index after the rewind", list)
+	}
+	if is, err := st.IsExistUTXO(2); err != nil {
+		t.Fatal(err)
+	} else if !is {
+		t.Fatal("utxo of the remained height is removed by the rewind")
+	}
+}
+
+func Test_StoreRewindPruned(t *testing.T) {
+	coord := common.NewCoordinate(0, 0)
+	st, err := NewStoreWithDB(db.NewMemoryDB(), 1, data.NewAccounter(coord), data.NewTransactor(coord), data.NewEventer(coord))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	st.keepBlocks = 3
+
+	var addr common.Address
+	addr[0] = 1
+	if err := st.StoreGenesis(hash.Hash([]byte("genesis")), data.NewContextData(nil, nil), map[string][]byte{"consensus": []byte{0}}); err != nil {
+		t.Fatal(err)
+	}
+	for h := uint32(1); h <= 8; h++ {
+		ctd := data.NewContextData(nil, nil)
+		ctd.SeqMap[addr] = uint64(h)
+		testStoreData(t, st, h, ctd)
+	}
+	if st.PrunedHeight() != 5 {
+		t.Fatal("invalid pruned height", st.PrunedHeight())
+	}
+	if err := st.Rewind(4); err != ErrPrunedData {
+		t.Fatal("rewind across the pruned height is not rejected", err)
+	}
+	if st.Height() != 8 {
+		t.Fatal("height is changed by the rejected rewind", st.Height())
+	}
+	if err := st.Rewind(5); err != nil {
+		t.Fatal(err)
+	}
+	if seq := st.Seq(addr); seq != 5 {
+		t.Fatal("invalid seq after the rewind to the pruned height", seq)
+	}
+	if _, err := st.Data(5); err != ErrPrunedData {
+		t.Fatal("pruned data is returned", err)
+	}
+}
+
+func testStoreData(t *testing.T, st *Store, height uint32, ctd *data.ContextData) {
+	cd := &chain.Data{
+		Header: &block.Header{
+			Base: chain.Base{
+				Height_:    height,
+				Timestamp_: uint64(height),
+			},
+		},
+		Body: &block.Body{
+			Transactions:          []transaction.Transaction{},
+			TransactionSignatures: [][]common.Signature{},
+			Tran:                  st.Transactor(),
+		},
+	}
+	if err := st.StoreData(cd, ctd, map[string][]byte{"consensus": []byte{byte(height)}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_StoreMigration(t *testing.T) {
+	coord := common.NewCoordinate(0, 0)
+	kv := db.NewMemoryDB()
+
+	var addr common.Address
+	addr[0] = 1
+	var deleted common.Address
+	deleted[0] = 2
+	// the layout of the format version 1 that has no stored version
+	if err := kv.Update(func(txn db.Txn) error {
+		if err := txn.Set([]byte("height"), util.Uint32ToBytes(3)); err != nil {
+			return err
+		}
+		if err := txn.Set(toAccountSeqKey(addr), util.Uint64ToBytes(3)); err != nil {
+			return err
+		}
+		Amount := amount.NewCoinAmount(7, 0).Bytes()
+		if err := txn.Set(toLockedBalanceKey(addr, 10), Amount); err != nil {
+			return err
+		}
+		if err := txn.Set(toPreviousLockedBalanceHeightKey(10, addr), Amount); err != nil {
+			return err
+		}
+		if err := txn.Set(toCustomData("consensus"), []byte{3}); err != nil {
+			return err
+		}
+		if err := txn.Set(toCustomData("chaincoord"), []byte{1}); err != nil {
+			return err
+		}
+		return txn.Set(toAccountNameKey("deleted"), deleted[:])
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := NewStoreWithDB(kv, 1, data.NewAccounter(coord), data.NewTransactor(coord), data.NewEventer(coord))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	if err := kv.View(func(txn db.Txn) error {
+		value, err := txn.Get([]byte("storeversion"))
+		if err != nil {
+			return err
+		}
+		if util.BytesToUint32(value) != storeFormatVersion {
+			t.Error("invalid store version after the migration", util.BytesToUint32(value))
+		}
+		if _, err := txn.Get(toPreviousLockedBalanceHeightKey(10, addr)); err != db.ErrNotExistKey {
+			t.Error("previous locked balance height key is remained", err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if list, err := st.LockedBalances(addr); err != nil {
+		t.Fatal(err)
+	} else if len(list) != 1 || list[0].UnlockHeight != 10 || !list[0].Amount.Equal(amount.NewCoinAmount(7, 0)) {
+		t.Fatal("invalid locked balances after the migration", list)
+	}
+	if list, err := st.LockedBalancesByHeight(10); err != nil {
+		t.Fatal(err)
+	} else if len(list) != 1 || !list[0].Address.Equal(addr) {
+		t.Fatal("invalid locked balance index after the migration", list)
+	}
+	if bs := st.StateCustomData("consensus"); len(bs) != 1 || bs[0] != 3 {
+		t.Fatal("custom data of blocks is not moved to the state", bs)
+	}
+	if bs := st.CustomData("chaincoord"); len(bs) != 1 || bs[0] != 1 {
+		t.Fatal("local custom data is changed by the migration", bs)
+	}
+	if is, err := st.IsExistAccountName("deleted"); err != nil {
+		t.Fatal(err)
+	} else if is {
+		t.Fatal("name of the deleted account is remained")
+	}
+
+	root, err := st.StateRootHash(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, proof, height, err := st.Prove(CustomDataStateKey("consensus"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height != 3 || !statetree.VerifyProof(root, CustomDataStateKey("consensus"), value, proof) {
+		t.Fatal("invalid proof of the migrated state tree")
+	}
+
+	// the migrated store keeps storing blocks on the built state tree
+	ctd := data.NewContextData(nil, nil)
+	ctd.SeqMap[addr] = 4
+	testStoreData(t, st, 4, ctd)
+	if _, err := st.StateRootHash(4); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,160 @@
+package kernel
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/fletaio/common/util"
+	"github.com/fletaio/core/db"
+)
+
+// undoTxn records the previous values of all keys updated through it
+// It is used to build the undo journal of the block that rewinds the store to the previous height
+type undoTxn struct {
+	txn     db.Txn
+	keyMap  map[string]bool
+	entries []*undoEntry
+}
+
+func newUndoTxn(txn db.Txn) *undoTxn {
+	return &undoTxn{
+		txn:     txn,
+		keyMap:  map[string]bool{},
+		entries: []*undoEntry{},
+	}
+}
+
+// Get returns the value of the key
+func (ut *undoTxn) Get(key []byte) ([]byte, error) {
+	return ut.txn.Get(key)
+}
+
+// Iterate calls the function with keys that have the prefix from the begin key
+func (ut *undoTxn) Iterate(begin []byte, prefix []byte, fn func(key []byte, value []byte) error) error {
+	return ut.txn.Iterate(begin, prefix, fn)
+}
+
+// Set records the previous value of the key and updates it
+func (ut *undoTxn) Set(key []byte, value []byte) error {
+	if err := ut.record(key); err != nil {
+		return err
+	}
+	return ut.txn.Set(key, value)
+}
+
+// Delete records the previous value of the key and deletes it
+func (ut *undoTxn) Delete(key []byte) error {
+	if err := ut.record(key); err != nil {
+		return err
+	}
+	return ut.txn.Delete(key)
+}
+
+func (ut *undoTxn) record(key []byte) error {
+	if ut.keyMap[string(key)] {
+		return nil
+	}
+	entry := &undoEntry{
+		Key: make([]byte, len(key)),
+	}
+	copy(entry.Key, key)
+	value, err := ut.txn.Get(key)
+	if err != nil {
+		if err != db.ErrNotExistKey {
+			return err
+		}
+	} else {
+		entry.IsExist = true
+		entry.Value = value
+	}
+	ut.keyMap[string(key)] = true
+	ut.entries = append(ut.entries, entry)
+	return nil
+}
+
+// Bytes returns the serialized undo journal
+func (ut *undoTxn) Bytes() ([]byte, error) {
+	var buffer bytes.Buffer
+	if _, err := util.WriteUint32(&buffer, uint32(len(ut.entries))); err != nil {
+		return nil, err
+	}
+	for _, e := range ut.entries {
+		if _, err := e.WriteTo(&buffer); err != nil {
+			return nil, err
+		}
+	}
+	return buffer.Bytes(), nil
+}
+
+// undoEntry is the previous state of the key before applying the block
+type undoEntry struct {
+	Key     []byte
+	IsExist bool
+	Value   []byte
+}
+
+// WriteTo is a serialization function
+func (e *undoEntry) WriteTo(w io.Writer) (int64, error) {
+	var wrote int64
+	if n, err := util.WriteBytes(w, e.Key); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteBool(w, e.IsExist); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if e.IsExist {
+		if n, err := util.WriteBytes(w, e.Value); err != nil {
+			return wrote, err
+		} else {
+			wrote += n
+		}
+	}
+	return wrote, nil
+}
+
+// ReadFrom is a deserialization function
+func (e *undoEntry) ReadFrom(r io.Reader) (int64, error) {
+	var read int64
+	if bs, n, err := util.ReadBytes(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		e.Key = bs
+	}
+	if v, n, err := util.ReadBool(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		e.IsExist = v
+	}
+	if e.IsExist {
+		if bs, n, err := util.ReadBytes(r); err != nil {
+			return read, err
+		} else {
+			read += n
+			e.Value = bs
+		}
+	}
+	return read, nil
+}
+
+func readUndoEntries(bs []byte) ([]*undoEntry, error) {
+	r := bytes.NewReader(bs)
+	Len, _, err := util.ReadUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*undoEntry, 0, Len)
+	for i := 0; i < int(Len); i++ {
+		e := &undoEntry{}
+		if _, err := e.ReadFrom(r); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
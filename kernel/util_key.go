@@ -14,6 +14,9 @@ var (
 	tagHeightHeader        = []byte{1, 2}
 	tagHeightData          = []byte{1, 3}
 	tagHashHeight          = []byte{1, 4}
+	tagHeightUndo          = []byte{1, 5}
+	tagHeightStateRoot     = []byte{1, 6}
+	tagHeightStaleNodes    = []byte{1, 7}
 	tagAccount             = []byte{2, 0}
 	tagAccountName         = []byte{2, 1}
 	tagAccountSeq          = []byte{2, 2}
@@ -21,9 +24,17 @@ var (
 	tagAccountData         = []byte{2, 4}
 	tagUTXO                = []byte{3, 0}
 	tagCustomData          = []byte{4, 0}
+	tagStateCustomData     = []byte{4, 1}
 	tagEvent               = []byte{5, 0}
 	tagLockedBalance       = []byte{6, 0}
 	tagLockedBalanceHeight = []byte{6, 1}
+	tagStateNode           = []byte{7, 0}
+	tagStaleNode           = []byte{7, 1}
+	tagTxHash              = []byte{8, 0}
+	tagAddressTx           = []byte{8, 1}
+	tagPublicHashTx        = []byte{8, 2}
+	tagPoolItem            = []byte{9, 0}
+	tagPoolBundle          = []byte{9, 1}
 )
 
 func toHeightDataKey(height uint32) []byte {
@@ -47,6 +58,27 @@ func toHeightHashKey(height uint32) []byte {
 	return bs
 }
 
+func toHeightUndoKey(height uint32) []byte {
+	bs := make([]byte, 6)
+	copy(bs, tagHeightUndo)
+	binary.LittleEndian.PutUint32(bs[2:], height)
+	return bs
+}
+
+func toHeightStateRootKey(height uint32) []byte {
+	bs := make([]byte, 6)
+	copy(bs, tagHeightStateRoot)
+	binary.LittleEndian.PutUint32(bs[2:], height)
+	return bs
+}
+
+func toHeightStaleNodesKey(height uint32) []byte {
+	bs := make([]byte, 6)
+	copy(bs, tagHeightStaleNodes)
+	binary.LittleEndian.PutUint32(bs[2:], height)
+	return bs
+}
+
 func toHashHeightKey(h hash.Hash256) []byte {
 	bs := make([]byte, 34)
 	copy(bs, tagHashHeight)
@@ -107,6 +139,13 @@ func toCustomData(key string) []byte {
 	return bs
 }
 
+func toStateCustomDataKey(key string) []byte {
+	bs := make([]byte, 2+len(key))
+	copy(bs, tagStateCustomData)
+	copy(bs[2:], []byte(key))
+	return bs
+}
+
 func toEventKey(id uint64) []byte {
 	bs := make([]byte, 10)
 	copy(bs, tagEvent)
@@ -137,14 +176,14 @@ func fromLockedBalanceKey(bs []byte) (common.Address, uint32) {
 
 func toLockedBalanceHeightPrefix(Height uint32) []byte {
 	bs := make([]byte, 6)
-	copy(bs, tagLockedBalance)
+	copy(bs, tagLockedBalanceHeight)
 	binary.LittleEndian.PutUint32(bs[2:], Height)
 	return bs
 }
 
 func toLockedBalanceHeightKey(UnlockHeight uint32, Address common.Address) []byte {
 	bs := make([]byte, 6+common.AddressSize)
-	copy(bs, tagLockedBalance)
+	copy(bs, tagLockedBalanceHeight)
 	binary.LittleEndian.PutUint32(bs[2:], UnlockHeight)
 	copy(bs[6:], Address[:])
 	return bs
@@ -155,3 +194,73 @@ func fromLockedBalanceHeightKey(bs []byte) (common.Address, uint32) {
 	copy(addr[:], bs[6:])
 	return addr, util.BytesToUint32(bs[2:])
 }
+
+func toStateNodeKey(h hash.Hash256) []byte {
+	bs := make([]byte, 34)
+	copy(bs, tagStateNode)
+	copy(bs[2:], h[:])
+	return bs
+}
+
+func toStaleNodeKey(h hash.Hash256) []byte {
+	bs := make([]byte, 34)
+	copy(bs, tagStaleNode)
+	copy(bs[2:], h[:])
+	return bs
+}
+
+func toTxHashKey(h hash.Hash256) []byte {
+	bs := make([]byte, 34)
+	copy(bs, tagTxHash)
+	copy(bs[2:], h[:])
+	return bs
+}
+
+func toAddressTxPrefix(addr common.Address) []byte {
+	bs := make([]byte, 2+common.AddressSize)
+	copy(bs, tagAddressTx)
+	copy(bs[2:], addr[:])
+	return bs
+}
+
+func toAddressTxKey(addr common.Address, coord *common.Coordinate) []byte {
+	return toIndexTxKey(toAddressTxPrefix(addr), coord)
+}
+
+func toPublicHashTxPrefix(pubhash common.PublicHash) []byte {
+	bs := make([]byte, 2+len(pubhash))
+	copy(bs, tagPublicHashTx)
+	copy(bs[2:], pubhash[:])
+	return bs
+}
+
+func toPublicHashTxKey(pubhash common.PublicHash, coord *common.Coordinate) []byte {
+	return toIndexTxKey(toPublicHashTxPrefix(pubhash), coord)
+}
+
+// toIndexTxKey uses the big endian height and index to iterate keys by the order of the coordinate
+func toIndexTxKey(prefix []byte, coord *common.Coordinate) []byte {
+	bs := make([]byte, len(prefix)+6)
+	copy(bs, prefix)
+	binary.BigEndian.PutUint32(bs[len(prefix):], coord.Height)
+	binary.BigEndian.PutUint16(bs[len(prefix)+4:], coord.Index)
+	return bs
+}
+
+func fromIndexTxKey(bs []byte) *common.Coordinate {
+	return common.NewCoordinate(binary.BigEndian.Uint32(bs[len(bs)-6:]), binary.BigEndian.Uint16(bs[len(bs)-2:]))
+}
+
+func toPoolItemKey(h hash.Hash256) []byte {
+	bs := make([]byte, 34)
+	copy(bs, tagPoolItem)
+	copy(bs[2:], h[:])
+	return bs
+}
+
+func toPoolBundleKey(h hash.Hash256) []byte {
+	bs := make([]byte, 34)
+	copy(bs, tagPoolBundle)
+	copy(bs[2:], h[:])
+	return bs
+}
@@ -8,4 +8,5 @@ import (
 var (
 	ErrExceedHashCount  = errors.New("exceed hash count")
 	ErrInvalidHashCount = errors.New("invalid hash count")
+	ErrInvalidHashIndex = errors.New("invalid hash index")
 )
@@ -0,0 +1,70 @@
+package level
+
+import (
+	"github.com/fletaio/common/hash"
+)
+
+// BuildProof returns the hash groups of each level from the bottom to the root that include the hash of the index
+func BuildProof(hashes []hash.Hash256, index int) ([][]hash.Hash256, error) {
+	if len(hashes) > 65536 {
+		return nil, ErrExceedHashCount
+	}
+	if len(hashes) == 0 {
+		return nil, ErrInvalidHashCount
+	}
+	if index < 0 || index >= len(hashes) {
+		return nil, ErrInvalidHashIndex
+	}
+
+	proof := make([][]hash.Hash256, 0, 4)
+	lv := hashes
+	idx := index
+	for i := 0; i < 4; i++ {
+		begin := (idx / hashPerLevel) * hashPerLevel
+		last := begin + hashPerLevel
+		if last > len(lv) {
+			last = len(lv)
+		}
+		group := make([]hash.Hash256, last-begin)
+		copy(group, lv[begin:last])
+		proof = append(proof, group)
+
+		if i < 3 {
+			next, err := buildLevel(lv)
+			if err != nil {
+				return nil, err
+			}
+			lv = next
+			idx /= hashPerLevel
+		}
+	}
+	return proof, nil
+}
+
+// VerifyProof checks that the leaf hash of the index is included in the level root hash using the proof
+func VerifyProof(root hash.Hash256, leaf hash.Hash256, index int, proof [][]hash.Hash256) bool {
+	if len(proof) != 4 {
+		return false
+	}
+	if index < 0 || index >= 65536 {
+		return false
+	}
+	h := leaf
+	idx := index
+	for _, group := range proof {
+		pos := idx % hashPerLevel
+		if pos >= len(group) {
+			return false
+		}
+		if !group[pos].Equal(h) {
+			return false
+		}
+		v, err := Hash16(group)
+		if err != nil {
+			return false
+		}
+		h = v
+		idx /= hashPerLevel
+	}
+	return h.Equal(root)
+}
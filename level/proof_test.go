@@ -0,0 +1,91 @@
+package level
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/fletaio/common/hash"
+)
+
+func testHashes(count int) []hash.Hash256 {
+	hashes := make([]hash.Hash256, count)
+	for i := range hashes {
+		hashes[i] = hash.Hash([]byte("leaf" + strconv.Itoa(i)))
+	}
+	return hashes
+}
+
+func Test_BuildProof(t *testing.T) {
+	for _, count := range []int{1, 2, 15, 16, 17, 255, 257, 1000} {
+		hashes := testHashes(count)
+		root, err := BuildLevelRoot(hashes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, index := range []int{0, count / 2, count - 1} {
+			proof, err := BuildProof(hashes, index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !VerifyProof(root, hashes[index], index, proof) {
+				t.Fatal("valid proof is not verified", count, index)
+			}
+			if count > 1 {
+				other := (index + 1) % count
+				if VerifyProof(root, hashes[index], other, proof) {
+					t.Fatal("proof is verified at the other index", count, index, other)
+				}
+				if VerifyProof(root, hashes[other], index, proof) {
+					t.Fatal("proof is verified with the other leaf", count, index, other)
+				}
+			}
+		}
+	}
+}
+
+func Test_VerifyProofTampered(t *testing.T) {
+	hashes := testHashes(300)
+	root, err := BuildLevelRoot(hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := 290
+	proof, err := BuildProof(hashes, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for lv, group := range proof {
+		for i := range group {
+			if lv == 0 && i == index%hashPerLevel {
+				continue
+			}
+			tampered := make([][]hash.Hash256, len(proof))
+			for j := range proof {
+				tampered[j] = make([]hash.Hash256, len(proof[j]))
+				copy(tampered[j], proof[j])
+			}
+			tampered[lv][i][0] ^= 1
+			if VerifyProof(root, hashes[index], index, tampered) {
+				t.Fatal("proof that has the tampered sibling is verified", lv, i)
+			}
+		}
+	}
+	if VerifyProof(root, hashes[index], index, proof[:3]) {
+		t.Fatal("proof that has the missing level is verified")
+	}
+	if VerifyProof(root, hashes[index], index, append(proof[:3:3], append(proof[3], hashes[0]))) {
+		t.Fatal("proof that has the extended root group is verified")
+	}
+}
+
+func Test_BuildProofInvalid(t *testing.T) {
+	if _, err := BuildProof(nil, 0); err != ErrInvalidHashCount {
+		t.Fatal("empty hashes are not rejected", err)
+	}
+	if _, err := BuildProof(testHashes(3), 3); err != ErrInvalidHashIndex {
+		t.Fatal("out of range index is not rejected", err)
+	}
+	if _, err := BuildProof(testHashes(3), -1); err != ErrInvalidHashIndex {
+		t.Fatal("negative index is not rejected", err)
+	}
+}
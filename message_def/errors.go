@@ -0,0 +1,8 @@
+package message_def
+
+import "errors"
+
+// message errors
+var (
+	ErrInvalidSignatureCount = errors.New("invalid signature count")
+)
@@ -0,0 +1,73 @@
+package message_def
+
+import (
+	"io"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/util"
+	"github.com/fletaio/core/data"
+	"github.com/fletaio/core/transaction"
+	"github.com/fletaio/framework/message"
+)
+
+// BundleMessage is a message for transactions that are included to the same block or not at all
+type BundleMessage struct {
+	Txs  []transaction.Transaction
+	Sigs [][]common.Signature
+	Tran *data.Transactor
+}
+
+// Type returns the type of the message
+func (b *BundleMessage) Type() message.Type {
+	return BundleMessageType
+}
+
+// WriteTo is a serialization function
+func (b *BundleMessage) WriteTo(w io.Writer) (int64, error) {
+	var wrote int64
+	if len(b.Sigs) != len(b.Txs) {
+		return wrote, ErrInvalidSignatureCount
+	}
+	if n, err := util.WriteUint16(w, uint16(len(b.Txs))); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	for i, tx := range b.Txs {
+		msg := &TransactionMessage{
+			Tx:   tx,
+			Sigs: b.Sigs[i],
+		}
+		if n, err := msg.WriteTo(w); err != nil {
+			return wrote, err
+		} else {
+			wrote += n
+		}
+	}
+	return wrote, nil
+}
+
+// ReadFrom is a deserialization function
+func (b *BundleMessage) ReadFrom(r io.Reader) (int64, error) {
+	var read int64
+	if Len, n, err := util.ReadUint16(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		b.Txs = make([]transaction.Transaction, 0, Len)
+		b.Sigs = make([][]common.Signature, 0, Len)
+		for i := 0; i < int(Len); i++ {
+			msg := &TransactionMessage{
+				Tran: b.Tran,
+			}
+			if n, err := msg.ReadFrom(r); err != nil {
+				return read, err
+			} else {
+				read += n
+				b.Txs = append(b.Txs, msg.Tx)
+				b.Sigs = append(b.Sigs, msg.Sigs)
+			}
+		}
+	}
+	return read, nil
+}
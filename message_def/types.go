@@ -9,4 +9,5 @@ var (
 	BlockReqMessageType    = message.DefineType("fleta.BlockReq")
 	TransactionMessageType = message.DefineType("fleta.Transaction")
 	PingMessageType        = message.DefineType("fleta.Ping")
+	BundleMessageType      = message.DefineType("fleta.Bundle")
 )
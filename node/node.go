@@ -13,6 +13,7 @@ import (
 	"github.com/fletaio/core/transaction"
 
 	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
 
 	"github.com/fletaio/framework/router"
 
@@ -62,6 +63,7 @@ func NewNode(Config *Config, kn *kernel.Kernel) (*Node, error) {
 		runEnd: make(chan struct{}),
 	}
 	nd.mm.SetCreator(message_def.TransactionMessageType, nd.messageCreator)
+	nd.mm.SetCreator(message_def.BundleMessageType, nd.messageCreator)
 	nd.cm.Mesh = pm
 	nd.pm.RegisterEventHandler(nd.cm)
 	nd.pm.RegisterEventHandler(nd)
@@ -162,6 +164,27 @@ func (nd *Node) CommitTransaction(tx transaction.Transaction, sigs []common.Sign
 	return <-errCh
 }
 
+// CommitTransactions adds transactions in the order of the sequence and broadcasts added transactions
+func (nd *Node) CommitTransactions(txs []transaction.Transaction, sigs [][]common.Signature) []error {
+	errs := nd.kn.AddTransactions(txs, sigs)
+	for i, err := range errs {
+		if err == nil {
+			nd.pm.BroadCastLimit(&message_def.TransactionMessage{
+				Tx:   txs[i],
+				Sigs: sigs[i],
+				Tran: nd.kn.Transactor(),
+			}, 7)
+		}
+	}
+	return errs
+}
+
+// CommitBundle adds transactions as the bundle that is included to the same block or not at all
+// The bundle is broadcasted by the kernel when it is added
+func (nd *Node) CommitBundle(txs []transaction.Transaction, sigs [][]common.Signature) (hash.Hash256, error) {
+	return nd.kn.AddBundle(txs, sigs)
+}
+
 // OnConnected is called after a new peer is connected
 func (nd *Node) OnConnected(p mesh.Peer) {
 }
@@ -197,6 +220,13 @@ func (nd *Node) handleMessage(p mesh.Peer, m message.Message) error {
 			return err
 		}
 		return nil
+	case *message_def.BundleMessage:
+		if _, err := nd.kn.AddBundle(msg.Txs, msg.Sigs); err != nil {
+			if err != kernel.ErrExistBundle && err != txpool.ErrExistTransaction {
+				return err
+			}
+		}
+		return nil
 	default:
 		return message.ErrUnhandledMessage
 	}
@@ -212,6 +242,14 @@ func (nd *Node) messageCreator(r io.Reader, t message.Type) (message.Message, er
 			return nil, err
 		}
 		return p, nil
+	case message_def.BundleMessageType:
+		p := &message_def.BundleMessage{
+			Tran: nd.kn.Transactor(),
+		}
+		if _, err := p.ReadFrom(r); err != nil {
+			return nil, err
+		}
+		return p, nil
 	default:
 		return nil, message.ErrUnknownMessage
 	}
@@ -240,6 +278,15 @@ func (nd *Node) DoTransactionBroadcast(kn *kernel.Kernel, msg *message_def.Trans
 	nd.pm.BroadCast(msg)
 }
 
+// DoBundleBroadcast called when a bundle need to be broadcast
+func (nd *Node) DoBundleBroadcast(kn *kernel.Kernel, msg *message_def.BundleMessage) {
+	nd.pm.BroadCast(msg)
+}
+
+// OnContextDivergence called when the context hash of the block is not matched with the local execution
+func (nd *Node) OnContextDivergence(kn *kernel.Kernel, report *kernel.DivergenceReport) {
+}
+
 // DebugLog TEMP
 func (nd *Node) DebugLog(kn *kernel.Kernel, args ...interface{}) {
 }
@@ -943,6 +943,14 @@ func (ob *Observer) AfterPushTransaction(kn *kernel.Kernel, tx transaction.Trans
 func (ob *Observer) DoTransactionBroadcast(kn *kernel.Kernel, msg *message_def.TransactionMessage) {
 }
 
+// DoBundleBroadcast called when a bundle need to be broadcast
+func (ob *Observer) DoBundleBroadcast(kn *kernel.Kernel, msg *message_def.BundleMessage) {
+}
+
+// OnContextDivergence called when the context hash of the block is not matched with the local execution
+func (ob *Observer) OnContextDivergence(kn *kernel.Kernel, report *kernel.DivergenceReport) {
+}
+
 // DebugLog TEMP
 func (ob *Observer) DebugLog(kn *kernel.Kernel, args ...interface{}) {
 }
@@ -6,6 +6,7 @@ import (
 )
 
 // Rewarder procceses rewards of the target height
+// The returned save data is committed to the state root, so it should be the same for the same state
 type Rewarder interface {
 	ProcessReward(Formulator common.Address, ctx *data.Context) ([]byte, error)
 	ApplyGenesis(ctx *data.ContextData) ([]byte, error)
@@ -2,6 +2,7 @@ package reward
 
 import (
 	"bytes"
+	"sort"
 
 	"github.com/fletaio/common"
 	"github.com/fletaio/common/util"
@@ -60,6 +61,11 @@ func (rd *TestNetRewarder) ProcessReward(addr common.Address, ctx *data.Context)
 		case consensus.HyperFormulatorType:
 			PowerSum := frAcc.Amount.MulC(int64(policy.HyperEfficiency1000)).DivC(1000)
 
+			HyperPolicy, err := consensus.EffectiveHyperPolicy(ctx, frAcc, ctx.TargetHeight())
+			if err != nil {
+				return nil, err
+			}
+
 			keys, err := ctx.AccountDataKeys(addr, consensus.TagStaking)
 			if err != nil {
 				return nil, err
@@ -79,7 +85,7 @@ func (rd *TestNetRewarder) ProcessReward(addr common.Address, ctx *data.Context)
 						rd.removeRewardPower(StakingAddress)
 					} else {
 						StakingPower := StakingAmount.MulC(int64(policy.StakingEfficiency1000)).DivC(1000)
-						ComissionPower := StakingPower.MulC(int64(frAcc.Policy.CommissionRatio1000)).DivC(1000)
+						ComissionPower := StakingPower.MulC(int64(HyperPolicy.CommissionRatio1000)).DivC(1000)
 
 						if bs := ctx.AccountData(addr, consensus.ToAutoStakingKey(StakingAddress)); len(bs) > 0 && bs[0] == 1 {
 							rd.addStakingPower(addr, StakingAddress, StakingPower.Sub(ComissionPower))
@@ -111,9 +117,22 @@ func (rd *TestNetRewarder) ProcessReward(addr common.Address, ctx *data.Context)
 					return nil, err
 				}
 			} else {
-				frAcc := acc.(*consensus.FormulationAccount)
-				frAcc.AddBalance(PowerSum.Mul(Ratio).Div(amount.COIN))
-				//log.Println("AddBalance", frAcc.Address().String(), PowerSum.Mul(Ratio).Div(amount.COIN).String())
+				// powers of stakers are also kept in the map, so the account is not always a formulation account
+				// the formulation account of the previous version doesn't have the reward address and it receives rewards itself
+				Reward := PowerSum.Mul(Ratio).Div(amount.COIN)
+				if frAcc, is := acc.(*consensus.FormulationAccount); is && !frAcc.RewardAddress.Equal(common.Address{}) && !frAcc.RewardAddress.Equal(frAcc.Address()) {
+					if rewardAcc, err := ctx.Account(frAcc.RewardAddress); err != nil {
+						if err != data.ErrNotExistAccount {
+							return nil, err
+						}
+						frAcc.AddBalance(Reward)
+					} else {
+						rewardAcc.AddBalance(Reward)
+					}
+				} else {
+					acc.AddBalance(Reward)
+				}
+				//log.Println("AddBalance", acc.Address().String(), Reward.String())
 			}
 			rd.removeRewardPower(RewardAddress)
 		}
@@ -185,14 +204,15 @@ func (rd *TestNetRewarder) buildSaveData() ([]byte, error) {
 	if _, err := util.WriteUint32(&buffer, rd.LastPaidHeight); err != nil {
 		return nil, err
 	}
+	// maps are written by the order of addresses because the save data is committed to the state root
 	if _, err := util.WriteUint32(&buffer, uint32(len(rd.PowerMap))); err != nil {
 		return nil, err
 	} else {
-		for addr, PowerSum := range rd.PowerMap {
+		for _, addr := range sortedAddresses(rd.PowerMap) {
 			if _, err := addr.WriteTo(&buffer); err != nil {
 				return nil, err
 			}
-			if _, err := PowerSum.WriteTo(&buffer); err != nil {
+			if _, err := rd.PowerMap[addr].WriteTo(&buffer); err != nil {
 				return nil, err
 			}
 		}
@@ -200,18 +220,26 @@ func (rd *TestNetRewarder) buildSaveData() ([]byte, error) {
 	if _, err := util.WriteUint32(&buffer, uint32(len(rd.StakingPowerMap))); err != nil {
 		return nil, err
 	} else {
-		for addr, PowerMap := range rd.StakingPowerMap {
+		addrs := make([]common.Address, 0, len(rd.StakingPowerMap))
+		for addr := range rd.StakingPowerMap {
+			addrs = append(addrs, addr)
+		}
+		sort.Slice(addrs, func(i, j int) bool {
+			return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+		})
+		for _, addr := range addrs {
+			PowerMap := rd.StakingPowerMap[addr]
 			if _, err := addr.WriteTo(&buffer); err != nil {
 				return nil, err
 			}
 			if _, err := util.WriteUint32(&buffer, uint32(len(PowerMap))); err != nil {
 				return nil, err
 			} else {
-				for StakingAddress, PowerSum := range PowerMap {
+				for _, StakingAddress := range sortedAddresses(PowerMap) {
 					if _, err := StakingAddress.WriteTo(&buffer); err != nil {
 						return nil, err
 					}
-					if _, err := PowerSum.WriteTo(&buffer); err != nil {
+					if _, err := PowerMap[StakingAddress].WriteTo(&buffer); err != nil {
 						return nil, err
 					}
 				}
@@ -258,7 +286,7 @@ func (rd *TestNetRewarder) LoadFromSaveData(SaveData []byte) error {
 				return err
 			} else {
 				PowerMap := map[common.Address]*amount.Amount{}
-				for j := 0; j < int(Len2); i++ {
+				for j := 0; j < int(Len2); j++ {
 					var StakingAddress common.Address
 					if _, err := StakingAddress.ReadFrom(r); err != nil {
 						return err
@@ -275,3 +303,14 @@ func (rd *TestNetRewarder) LoadFromSaveData(SaveData []byte) error {
 	}
 	return nil
 }
+
+func sortedAddresses(PowerMap map[common.Address]*amount.Amount) []common.Address {
+	addrs := make([]common.Address, 0, len(PowerMap))
+	for addr := range PowerMap {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	return addrs
+}
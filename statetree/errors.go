@@ -0,0 +1,10 @@
+package statetree
+
+import "errors"
+
+// statetree errors
+var (
+	ErrNotExistNode    = errors.New("not exist node")
+	ErrInvalidNode     = errors.New("invalid node")
+	ErrInvalidTreePath = errors.New("invalid tree path")
+)
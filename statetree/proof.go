@@ -0,0 +1,138 @@
+package statetree
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/common/util"
+)
+
+// Proof is the merkle path of the key from the root of the state tree
+// When the path of the key ends with a leaf of the other key, the leaf is included to prove that the key is not exist
+type Proof struct {
+	Siblings       []hash.Hash256
+	IsOtherLeaf    bool
+	OtherKeyHash   hash.Hash256
+	OtherValueHash hash.Hash256
+}
+
+// WriteTo is a serialization function
+func (p *Proof) WriteTo(w io.Writer) (int64, error) {
+	var wrote int64
+	if n, err := util.WriteUint16(w, uint16(len(p.Siblings))); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	for _, h := range p.Siblings {
+		if n, err := h.WriteTo(w); err != nil {
+			return wrote, err
+		} else {
+			wrote += n
+		}
+	}
+	if n, err := util.WriteBool(w, p.IsOtherLeaf); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if p.IsOtherLeaf {
+		if n, err := p.OtherKeyHash.WriteTo(w); err != nil {
+			return wrote, err
+		} else {
+			wrote += n
+		}
+		if n, err := p.OtherValueHash.WriteTo(w); err != nil {
+			return wrote, err
+		} else {
+			wrote += n
+		}
+	}
+	return wrote, nil
+}
+
+// ReadFrom is a deserialization function
+func (p *Proof) ReadFrom(r io.Reader) (int64, error) {
+	var read int64
+	if Len, n, err := util.ReadUint16(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		if Len > treeDepth {
+			return read, ErrInvalidTreePath
+		}
+		p.Siblings = make([]hash.Hash256, Len)
+		for i := 0; i < int(Len); i++ {
+			if n, err := p.Siblings[i].ReadFrom(r); err != nil {
+				return read, err
+			} else {
+				read += n
+			}
+		}
+	}
+	if v, n, err := util.ReadBool(r); err != nil {
+		return read, err
+	} else {
+		read += n
+		p.IsOtherLeaf = v
+	}
+	if p.IsOtherLeaf {
+		if n, err := p.OtherKeyHash.ReadFrom(r); err != nil {
+			return read, err
+		} else {
+			read += n
+		}
+		if n, err := p.OtherValueHash.ReadFrom(r); err != nil {
+			return read, err
+		} else {
+			read += n
+		}
+	}
+	return read, nil
+}
+
+// MarshalJSON is a marshaler function
+func (p *Proof) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(`{`)
+	buffer.WriteString(`"siblings":`)
+	buffer.WriteString(`[`)
+	for i, h := range p.Siblings {
+		if i > 0 {
+			buffer.WriteString(`,`)
+		}
+		if bs, err := h.MarshalJSON(); err != nil {
+			return nil, err
+		} else {
+			buffer.Write(bs)
+		}
+	}
+	buffer.WriteString(`]`)
+	buffer.WriteString(`,`)
+	buffer.WriteString(`"is_other_leaf":`)
+	if bs, err := json.Marshal(p.IsOtherLeaf); err != nil {
+		return nil, err
+	} else {
+		buffer.Write(bs)
+	}
+	if p.IsOtherLeaf {
+		buffer.WriteString(`,`)
+		buffer.WriteString(`"other_key_hash":`)
+		if bs, err := p.OtherKeyHash.MarshalJSON(); err != nil {
+			return nil, err
+		} else {
+			buffer.Write(bs)
+		}
+		buffer.WriteString(`,`)
+		buffer.WriteString(`"other_value_hash":`)
+		if bs, err := p.OtherValueHash.MarshalJSON(); err != nil {
+			return nil, err
+		} else {
+			buffer.Write(bs)
+		}
+	}
+	buffer.WriteString(`}`)
+	return buffer.Bytes(), nil
+}
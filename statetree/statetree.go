@@ -0,0 +1,314 @@
+package statetree
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/fletaio/common/hash"
+)
+
+// The state tree is a sparse merkle tree of depth 256 that uses the hash of the key as its path
+// A subtree that has only one leaf is replaced by the leaf itself and an empty subtree is the zero hash
+// Every node is stored by its hash and the stored bytes are the preimage of the hash
+const (
+	leafNode   = byte(0)
+	branchNode = byte(1)
+	nodeSize   = 1 + 32 + 32
+	treeDepth  = 256
+)
+
+var emptyHash hash.Hash256
+
+// NodeStore provides stored nodes of the tree
+type NodeStore interface {
+	Node(h hash.Hash256) ([]byte, error)
+}
+
+// Update applies changes of the key-value map to the tree of the root and returns the new root, created nodes and stale nodes
+// The nil value deletes the key from the tree
+// Stale nodes are nodes of the tree of the root that are not used by the tree of the new root
+func Update(ns NodeStore, root hash.Hash256, kv map[string][]byte) (hash.Hash256, map[hash.Hash256][]byte, []hash.Hash256, error) {
+	entries := make([]*entry, 0, len(kv))
+	for k, v := range kv {
+		e := &entry{
+			KeyHash:  hash.Hash([]byte(k)),
+			IsDelete: v == nil,
+		}
+		if !e.IsDelete {
+			e.ValueHash = hash.Hash(v)
+		}
+		entries = append(entries, e)
+	}
+	sortEntries(entries)
+
+	u := &updater{
+		ns:       ns,
+		nodes:    map[hash.Hash256][]byte{},
+		replaced: map[hash.Hash256]bool{},
+	}
+	h, err := u.update(root, 0, entries)
+	if err != nil {
+		return hash.Hash256{}, nil, nil, err
+	}
+	stale := []hash.Hash256{}
+	for k := range u.replaced {
+		if _, has := u.nodes[k]; !has {
+			stale = append(stale, k)
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool {
+		return bytes.Compare(stale[i][:], stale[j][:]) < 0
+	})
+	return h, u.nodes, stale, nil
+}
+
+// Prove returns the proof of the key from the tree of the root
+func Prove(ns NodeStore, root hash.Hash256, key []byte) (*Proof, error) {
+	KeyHash := hash.Hash(key)
+	p := &Proof{
+		Siblings: []hash.Hash256{},
+	}
+	h := root
+	for depth := 0; depth < treeDepth; depth++ {
+		if h == emptyHash {
+			return p, nil
+		}
+		bs, err := ns.Node(h)
+		if err != nil {
+			return nil, err
+		}
+		if len(bs) != nodeSize {
+			return nil, ErrInvalidNode
+		}
+		switch bs[0] {
+		case leafNode:
+			var LeafKeyHash hash.Hash256
+			copy(LeafKeyHash[:], bs[1:33])
+			if !LeafKeyHash.Equal(KeyHash) {
+				p.IsOtherLeaf = true
+				p.OtherKeyHash = LeafKeyHash
+				copy(p.OtherValueHash[:], bs[33:])
+			}
+			return p, nil
+		case branchNode:
+			var left, right hash.Hash256
+			copy(left[:], bs[1:33])
+			copy(right[:], bs[33:])
+			if bitAt(KeyHash, depth) {
+				p.Siblings = append(p.Siblings, left)
+				h = right
+			} else {
+				p.Siblings = append(p.Siblings, right)
+				h = left
+			}
+		default:
+			return nil, ErrInvalidNode
+		}
+	}
+	return nil, ErrInvalidTreePath
+}
+
+// VerifyProof checks that the key has the value in the tree of the root
+// The nil value checks that the key is not exist in the tree
+func VerifyProof(root hash.Hash256, key []byte, value []byte, p *Proof) bool {
+	if len(p.Siblings) > treeDepth {
+		return false
+	}
+	KeyHash := hash.Hash(key)
+	var h hash.Hash256
+	if value != nil {
+		if p.IsOtherLeaf {
+			return false
+		}
+		h = leafHash(KeyHash, hash.Hash(value))
+	} else if p.IsOtherLeaf {
+		if p.OtherKeyHash.Equal(KeyHash) {
+			return false
+		}
+		for i := 0; i < len(p.Siblings); i++ {
+			if bitAt(p.OtherKeyHash, i) != bitAt(KeyHash, i) {
+				return false
+			}
+		}
+		h = leafHash(p.OtherKeyHash, p.OtherValueHash)
+	}
+	for i := len(p.Siblings) - 1; i >= 0; i-- {
+		if bitAt(KeyHash, i) {
+			h = branchHash(p.Siblings[i], h)
+		} else {
+			h = branchHash(h, p.Siblings[i])
+		}
+	}
+	return h.Equal(root)
+}
+
+type entry struct {
+	KeyHash   hash.Hash256
+	ValueHash hash.Hash256
+	IsDelete  bool
+}
+
+func sortEntries(entries []*entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].KeyHash[:], entries[j].KeyHash[:]) < 0
+	})
+}
+
+type updater struct {
+	ns       NodeStore
+	nodes    map[hash.Hash256][]byte
+	replaced map[hash.Hash256]bool
+}
+
+func (u *updater) node(h hash.Hash256) ([]byte, error) {
+	if bs, has := u.nodes[h]; has {
+		return bs, nil
+	}
+	bs, err := u.ns.Node(h)
+	if err != nil {
+		return nil, err
+	}
+	if len(bs) != nodeSize {
+		return nil, ErrInvalidNode
+	}
+	return bs, nil
+}
+
+func (u *updater) update(root hash.Hash256, depth int, entries []*entry) (hash.Hash256, error) {
+	if len(entries) == 0 {
+		return root, nil
+	}
+	if root == emptyHash {
+		return u.build(depth, entries)
+	}
+	bs, err := u.node(root)
+	if err != nil {
+		return hash.Hash256{}, err
+	}
+	// every node has the only position in the tree, so the updated node is replaced by the result
+	u.replaced[root] = true
+	switch bs[0] {
+	case leafNode:
+		old := &entry{}
+		copy(old.KeyHash[:], bs[1:33])
+		copy(old.ValueHash[:], bs[33:])
+		for _, e := range entries {
+			if e.KeyHash.Equal(old.KeyHash) {
+				return u.build(depth, entries)
+			}
+		}
+		list := make([]*entry, 0, len(entries)+1)
+		list = append(list, entries...)
+		list = append(list, old)
+		sortEntries(list)
+		return u.build(depth, list)
+	case branchNode:
+		var left, right hash.Hash256
+		copy(left[:], bs[1:33])
+		copy(right[:], bs[33:])
+		idx := splitEntries(entries, depth)
+		l, err := u.update(left, depth+1, entries[:idx])
+		if err != nil {
+			return hash.Hash256{}, err
+		}
+		r, err := u.update(right, depth+1, entries[idx:])
+		if err != nil {
+			return hash.Hash256{}, err
+		}
+		return u.branch(l, r)
+	default:
+		return hash.Hash256{}, ErrInvalidNode
+	}
+}
+
+func (u *updater) build(depth int, entries []*entry) (hash.Hash256, error) {
+	list := make([]*entry, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDelete {
+			list = append(list, e)
+		}
+	}
+	return u.buildSubtree(depth, list)
+}
+
+func (u *updater) buildSubtree(depth int, entries []*entry) (hash.Hash256, error) {
+	switch len(entries) {
+	case 0:
+		return emptyHash, nil
+	case 1:
+		bs := leafBytes(entries[0].KeyHash, entries[0].ValueHash)
+		h := hash.Hash(bs)
+		u.nodes[h] = bs
+		return h, nil
+	}
+	if depth >= treeDepth {
+		return hash.Hash256{}, ErrInvalidTreePath
+	}
+	idx := splitEntries(entries, depth)
+	l, err := u.buildSubtree(depth+1, entries[:idx])
+	if err != nil {
+		return hash.Hash256{}, err
+	}
+	r, err := u.buildSubtree(depth+1, entries[idx:])
+	if err != nil {
+		return hash.Hash256{}, err
+	}
+	return u.branch(l, r)
+}
+
+func (u *updater) branch(l hash.Hash256, r hash.Hash256) (hash.Hash256, error) {
+	if l == emptyHash && r == emptyHash {
+		return emptyHash, nil
+	}
+	if l == emptyHash || r == emptyHash {
+		other := l
+		if other == emptyHash {
+			other = r
+		}
+		bs, err := u.node(other)
+		if err != nil {
+			return hash.Hash256{}, err
+		}
+		if bs[0] == leafNode {
+			return other, nil
+		}
+	}
+	bs := branchBytes(l, r)
+	h := hash.Hash(bs)
+	u.nodes[h] = bs
+	return h, nil
+}
+
+func splitEntries(entries []*entry, depth int) int {
+	return sort.Search(len(entries), func(i int) bool {
+		return bitAt(entries[i].KeyHash, depth)
+	})
+}
+
+func bitAt(h hash.Hash256, depth int) bool {
+	return h[depth/8]&(0x80>>uint(depth%8)) != 0
+}
+
+func leafBytes(KeyHash hash.Hash256, ValueHash hash.Hash256) []byte {
+	bs := make([]byte, nodeSize)
+	bs[0] = leafNode
+	copy(bs[1:], KeyHash[:])
+	copy(bs[33:], ValueHash[:])
+	return bs
+}
+
+func branchBytes(left hash.Hash256, right hash.Hash256) []byte {
+	bs := make([]byte, nodeSize)
+	bs[0] = branchNode
+	copy(bs[1:], left[:])
+	copy(bs[33:], right[:])
+	return bs
+}
+
+func leafHash(KeyHash hash.Hash256, ValueHash hash.Hash256) hash.Hash256 {
+	return hash.Hash(leafBytes(KeyHash, ValueHash))
+}
+
+func branchHash(left hash.Hash256, right hash.Hash256) hash.Hash256 {
+	return hash.Hash(branchBytes(left, right))
+}
@@ -0,0 +1,77 @@
+package statetree
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/fletaio/common/hash"
+)
+
+type memoryNodeStore map[hash.Hash256][]byte
+
+func (ns memoryNodeStore) Node(h hash.Hash256) ([]byte, error) {
+	if bs, has := ns[h]; has {
+		return bs, nil
+	}
+	return nil, ErrNotExistNode
+}
+
+func (ns memoryNodeStore) update(t *testing.T, root hash.Hash256, kv map[string][]byte) hash.Hash256 {
+	h, nodes, stale, err := Update(ns, root, kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range stale {
+		delete(ns, k)
+	}
+	for k, v := range nodes {
+		ns[k] = v
+	}
+	return h
+}
+
+func Test_StateTree(t *testing.T) {
+	ns := memoryNodeStore{}
+	state := map[string][]byte{}
+	var root hash.Hash256
+	for i := 0; i < 300; i++ {
+		kv := map[string][]byte{}
+		for j := 0; j < 10; j++ {
+			key := strconv.Itoa((i*7 + j*13) % 500)
+			if (i+j)%4 == 0 {
+				kv[key] = nil
+				delete(state, key)
+			} else {
+				kv[key] = []byte(key + "-" + strconv.Itoa(i))
+				state[key] = kv[key]
+			}
+		}
+		root = ns.update(t, root, kv)
+	}
+
+	fresh := memoryNodeStore{}
+	if h := fresh.update(t, hash.Hash256{}, state); !h.Equal(root) {
+		t.Fatal("root is not same with the tree built at once")
+	}
+	if len(ns) != len(fresh) {
+		t.Fatal("stale nodes are remained", len(ns), len(fresh))
+	}
+
+	for i := 0; i < 500; i++ {
+		key := []byte(strconv.Itoa(i))
+		p, err := Prove(ns, root, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value := state[string(key)]
+		if !VerifyProof(root, key, value, p) {
+			t.Fatal("invalid proof", string(key))
+		}
+		if VerifyProof(root, key, []byte("wrong"), p) {
+			t.Fatal("wrong value is verified", string(key))
+		}
+		if value != nil && VerifyProof(root, key, nil, p) {
+			t.Fatal("existing key is verified as not exist", string(key))
+		}
+	}
+}
@@ -5,4 +5,6 @@ import "errors"
 // transaction errors
 var (
 	ErrExceedSignatureCount = errors.New("exceed signature count")
+	ErrNotValidYet          = errors.New("not valid yet")
+	ErrExpiredTransaction   = errors.New("expired transaction")
 )
@@ -6,6 +6,7 @@ import (
 
 	"github.com/fletaio/common/hash"
 	"github.com/fletaio/common/util"
+	"github.com/fletaio/core/amount"
 )
 
 // Transaction is an interface that defines common transaction functions
@@ -19,6 +20,12 @@ type Transaction interface {
 	IsUTXO() bool
 }
 
+// PriorityFeeTransaction is an interface that defines the priority fee that is paid in addition to the fee of the transaction type
+// Transactions that have higher fee are popped first from the transaction pool
+type PriorityFeeTransaction interface {
+	PriorityFee() *amount.Amount
+}
+
 // Base is the parts of transaction functions that are not changed by derived one
 type Base struct {
 	Type_      Type
@@ -0,0 +1,34 @@
+package transaction
+
+// WindowTransaction is an interface that defines the validity window of the transaction
+// The transaction can be included from NotBeforeHeight and cannot be included from ExpireHeight or after ExpireTimestamp
+// Zero values mean that the window is not limited by them
+type WindowTransaction interface {
+	NotBeforeHeight() uint32
+	ExpireHeight() uint32
+	ExpireTimestamp() uint64
+}
+
+// CheckWindow checks that the transaction can be included to the block of the height and the timestamp
+// If the timestamp is zero, the expire timestamp is not checked
+func CheckWindow(tx Transaction, Height uint32, Timestamp uint64) error {
+	wtx, is := tx.(WindowTransaction)
+	if !is {
+		return nil
+	}
+	if h := wtx.NotBeforeHeight(); h > 0 && Height < h {
+		return ErrNotValidYet
+	}
+	if h := wtx.ExpireHeight(); h > 0 && Height >= h {
+		return ErrExpiredTransaction
+	}
+	if t := wtx.ExpireTimestamp(); t > 0 && Timestamp > 0 && Timestamp >= t {
+		return ErrExpiredTransaction
+	}
+	return nil
+}
+
+// IsExpired returns that the transaction cannot be included to blocks after the height and the timestamp
+func IsExpired(tx Transaction, Height uint32, Timestamp uint64) bool {
+	return CheckWindow(tx, Height, Timestamp) == ErrExpiredTransaction
+}
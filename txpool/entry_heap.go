@@ -0,0 +1,53 @@
+package txpool
+
+import (
+	"github.com/fletaio/common"
+)
+
+// poolEntry is an UTXO model based transaction or the next transaction of an address in the heap
+type poolEntry struct {
+	addr  common.Address
+	item  *PoolItem
+	index int
+}
+
+// entryHeap is a max heap of entries by the order of the fee
+type entryHeap []*poolEntry
+
+func (h entryHeap) Len() int {
+	return len(h)
+}
+
+func (h entryHeap) Less(i, j int) bool {
+	return h[i].item.isPrior(h[j].item)
+}
+
+func (h entryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *entryHeap) Push(x interface{}) {
+	e := x.(*poolEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *entryHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return e
+}
+
+// evictionHeap is a min heap of entries by the order of the fee, so the entry of the top is evicted first
+type evictionHeap struct {
+	entryHeap
+}
+
+func (h evictionHeap) Less(i, j int) bool {
+	return h.entryHeap[j].item.isPrior(h.entryHeap[i].item)
+}
@@ -4,7 +4,11 @@ import "errors"
 
 // TransactionPool errors
 var (
-	ErrEmptyQueue            = errors.New("empty queue")
-	ErrNotAccountTransaction = errors.New("not account transaction")
-	ErrExistTransaction      = errors.New("exist transaction")
+	ErrEmptyQueue                 = errors.New("empty queue")
+	ErrNotAccountTransaction      = errors.New("not account transaction")
+	ErrExistTransaction           = errors.New("exist transaction")
+	ErrReplaceUnderpriced         = errors.New("replace underpriced")
+	ErrTooManyPendingTransactions = errors.New("too many pending transactions")
+	ErrTxPoolFull                 = errors.New("transaction pool full")
+	ErrDoubleSpendInPool          = errors.New("double spend in pool")
 )
@@ -0,0 +1,147 @@
+package txpool
+
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	"github.com/fletaio/common"
+	"github.com/fletaio/common/hash"
+	"github.com/fletaio/core/amount"
+	"github.com/fletaio/core/transaction"
+)
+
+// ItemInfo is the snapshot of the item in the transaction pool
+// ExpectedSeq is the next sequence of the address that is required to pop the account model based transaction
+type ItemInfo struct {
+	TxHash      hash.Hash256
+	Type        transaction.Type
+	Size        int64
+	Fee         *amount.Amount
+	PushedAt    int64
+	Age         time.Duration
+	IsUTXO      bool
+	From        common.Address
+	Seq         uint64
+	ExpectedSeq uint64
+	IsPending   bool
+}
+
+// Pending returns snapshots of items that can be popped by the sequence of the cache
+// UTXO model based transactions are ordered by the priority and account model based transactions are ordered by the address and the sequence
+func (tp *TransactionPool) Pending(SeqCache SeqCache) []*ItemInfo {
+	tp.Lock()
+	defer tp.Unlock()
+
+	list := tp.utxoInfos()
+	for _, addr := range tp.sortedAddresses() {
+		pending, _ := tp.accountInfos(addr, SeqCache)
+		list = append(list, pending...)
+	}
+	return list
+}
+
+// Queued returns snapshots of account model based transactions that are blocked by the sequence gap from the sequence of the cache
+func (tp *TransactionPool) Queued(SeqCache SeqCache) []*ItemInfo {
+	tp.Lock()
+	defer tp.Unlock()
+
+	list := []*ItemInfo{}
+	for _, addr := range tp.sortedAddresses() {
+		_, queued := tp.accountInfos(addr, SeqCache)
+		list = append(list, queued...)
+	}
+	return list
+}
+
+// PendingByAddress returns snapshots of pending and queued transactions of the address
+func (tp *TransactionPool) PendingByAddress(addr common.Address, SeqCache SeqCache) ([]*ItemInfo, []*ItemInfo) {
+	tp.Lock()
+	defer tp.Unlock()
+
+	return tp.accountInfos(addr, SeqCache)
+}
+
+func (tp *TransactionPool) utxoInfos() []*ItemInfo {
+	items := make([]*PoolItem, 0, len(tp.utxoEntryMap))
+	for _, e := range tp.utxoEntryMap {
+		items = append(items, e.item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].isPrior(items[j])
+	})
+
+	now := time.Now().UnixNano()
+	list := make([]*ItemInfo, 0, len(items))
+	for _, item := range items {
+		info := newItemInfo(item, now)
+		info.IsPending = true
+		list = append(list, info)
+	}
+	return list
+}
+
+// accountInfos returns snapshots of the address by the sequence and separates them at the first gap of the sequence
+func (tp *TransactionPool) accountInfos(addr common.Address, SeqCache SeqCache) ([]*ItemInfo, []*ItemInfo) {
+	pending := []*ItemInfo{}
+	queued := []*ItemInfo{}
+	bucket, has := tp.bucketMap[addr]
+	if !has {
+		return pending, queued
+	}
+	seqs := make([]uint64, 0, len(bucket.seqMap))
+	for seq := range bucket.seqMap {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool {
+		return seqs[i] < seqs[j]
+	})
+
+	now := time.Now().UnixNano()
+	expected := SeqCache.Seq(addr) + 1
+	isPending := true
+	for _, seq := range seqs {
+		// the transaction of the past sequence is removed when the block is connected
+		if seq < expected {
+			continue
+		}
+		info := newItemInfo(bucket.seqMap[seq], now)
+		info.From = addr
+		info.Seq = seq
+		if isPending && seq != expected {
+			isPending = false
+		}
+		info.ExpectedSeq = expected
+		info.IsPending = isPending
+		if isPending {
+			pending = append(pending, info)
+			expected++
+		} else {
+			queued = append(queued, info)
+		}
+	}
+	return pending, queued
+}
+
+func (tp *TransactionPool) sortedAddresses() []common.Address {
+	addrs := make([]common.Address, 0, len(tp.bucketMap))
+	for addr := range tp.bucketMap {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	return addrs
+}
+
+func newItemInfo(item *PoolItem, now int64) *ItemInfo {
+	return &ItemInfo{
+		TxHash:   item.TxHash,
+		Type:     item.Transaction.Type(),
+		Size:     item.Size,
+		Fee:      item.Fee,
+		PushedAt: item.PushedAt,
+		Age:      time.Duration(now - item.PushedAt),
+		IsUTXO:   item.Transaction.IsUTXO(),
+	}
+}
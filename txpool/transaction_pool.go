@@ -1,11 +1,16 @@
 package txpool
 
 import (
+	"bytes"
+	"container/heap"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/fletaio/common"
 	"github.com/fletaio/common/hash"
 	"github.com/fletaio/common/queue"
+	"github.com/fletaio/core/amount"
 	"github.com/fletaio/core/transaction"
 )
 
@@ -20,40 +25,106 @@ type UTXOTransaction interface {
 	VinIDs() []uint64
 }
 
+// FeeCalculator is a function type to calculate the effective fee of the transaction
+type FeeCalculator func(tx transaction.Transaction) *amount.Amount
+
+// Limits is the admission limits of the transaction pool and zero value means no limit
+type Limits struct {
+	MaxPendingPerAddress int
+	MaxSize              int
+	MaxBytes             int64
+}
+
+// Stats is the current status of the transaction pool
+type Stats struct {
+	Size         int
+	Bytes        int64
+	UTXOCount    int
+	AddressCount int
+}
+
 // TransactionPool provides a transaction queue
 // User can push transaction regardless of UTXO model based transactions or account model based transactions
+// Transactions are popped by the order of the fee and transactions that have the same fee are popped by FIFO
 // If the sequence of the account model based transaction is not reached to the next of the last sequence, it doens't poped
 type TransactionPool struct {
 	sync.Mutex
-	turnQ        *queue.Queue
-	numberQ      *queue.Queue
-	utxoQ        *queue.LinkedQueue
-	txidMap      map[hash.Hash256]bool
-	turnOutMap   map[bool]int
-	numberOutMap map[common.Address]int
-	bucketMap    map[common.Address]*queue.SortedQueue
+	feeCalculator FeeCalculator
+	limits        Limits
+	pushCount     uint64
+	bytes         int64
+	utxoHeap      *entryHeap
+	addrHeap      *entryHeap
+	utxoEntryMap  map[hash.Hash256]*poolEntry
+	addrEntryMap  map[common.Address]*poolEntry
+	evictHeap     *evictionHeap
+	utxoEvictMap  map[hash.Hash256]*poolEntry
+	addrEvictMap  map[common.Address]*poolEntry
+	itemMap       map[hash.Hash256]*PoolItem
+	bucketMap     map[common.Address]*accountBucket
+	utxoClaimMap  map[uint64]hash.Hash256
 }
 
 // NewTransactionPool returns a TransactionPool
 func NewTransactionPool() *TransactionPool {
 	tp := &TransactionPool{
-		turnQ:        queue.NewQueue(),
-		numberQ:      queue.NewQueue(),
-		utxoQ:        queue.NewLinkedQueue(),
-		txidMap:      map[hash.Hash256]bool{},
-		turnOutMap:   map[bool]int{},
-		numberOutMap: map[common.Address]int{},
-		bucketMap:    map[common.Address]*queue.SortedQueue{},
+		feeCalculator: priorityFee,
+		utxoHeap:      &entryHeap{},
+		addrHeap:      &entryHeap{},
+		utxoEntryMap:  map[hash.Hash256]*poolEntry{},
+		addrEntryMap:  map[common.Address]*poolEntry{},
+		evictHeap:     &evictionHeap{},
+		utxoEvictMap:  map[hash.Hash256]*poolEntry{},
+		addrEvictMap:  map[common.Address]*poolEntry{},
+		itemMap:       map[hash.Hash256]*PoolItem{},
+		bucketMap:     map[common.Address]*accountBucket{},
+		utxoClaimMap:  map[uint64]hash.Hash256{},
 	}
 	return tp
 }
 
+// priorityFee is the default fee calculator that uses the priority fee of the transaction only
+func priorityFee(tx transaction.Transaction) *amount.Amount {
+	if ptx, is := tx.(transaction.PriorityFeeTransaction); is {
+		if fee := ptx.PriorityFee(); fee != nil {
+			return fee
+		}
+	}
+	return amount.NewCoinAmount(0, 0)
+}
+
+// SetFeeCalculator changes the fee calculator that is used to order transactions that are pushed after it
+func (tp *TransactionPool) SetFeeCalculator(fn FeeCalculator) {
+	tp.Lock()
+	defer tp.Unlock()
+
+	tp.feeCalculator = fn
+}
+
+// SetLimits changes the admission limits of the transaction pool
+// Items that are already pushed are not evicted until the next push
+func (tp *TransactionPool) SetLimits(limits Limits) {
+	tp.Lock()
+	defer tp.Unlock()
+
+	tp.limits = limits
+}
+
 // IsExist checks that the transaction hash is inserted or not
 func (tp *TransactionPool) IsExist(TxHash hash.Hash256) bool {
 	tp.Lock()
 	defer tp.Unlock()
 
-	return tp.txidMap[TxHash]
+	_, has := tp.itemMap[TxHash]
+	return has
+}
+
+// Item returns the item of the transaction hash or nil if it is not exist
+func (tp *TransactionPool) Item(TxHash hash.Hash256) *PoolItem {
+	tp.Lock()
+	defer tp.Unlock()
+
+	return tp.itemMap[TxHash]
 }
 
 // Size returns the size of TxPool
@@ -61,86 +132,379 @@ func (tp *TransactionPool) Size() int {
 	tp.Lock()
 	defer tp.Unlock()
 
-	sum := 0
-	for _, v := range tp.turnOutMap {
-		sum += v
+	return len(tp.itemMap)
+}
+
+// Stats returns the current status of the transaction pool
+func (tp *TransactionPool) Stats() *Stats {
+	tp.Lock()
+	defer tp.Unlock()
+
+	return &Stats{
+		Size:         len(tp.itemMap),
+		Bytes:        tp.bytes,
+		UTXOCount:    len(tp.utxoEntryMap),
+		AddressCount: len(tp.bucketMap),
 	}
-	return tp.turnQ.Size() - sum
 }
 
 // Push inserts the transaction and signatures of it by base model and sequence
-// An UTXO model based transaction will be handled by FIFO
-// An account model based transaction will be sorted by the sequence value
+// An UTXO model based transaction will be ordered by the fee
+// An account model based transaction will be sorted by the sequence value in the address and the address will be ordered by the fee of the next transaction
 func (tp *TransactionPool) Push(t transaction.Transaction, sigs []common.Signature) error {
+	return tp.PushItem(&PoolItem{
+		Transaction: t,
+		TxHash:      t.Hash(),
+		Signatures:  sigs,
+		PushedAt:    time.Now().UnixNano(),
+	})
+}
+
+// PushItem inserts the item as same as Push but it keeps the pushed time of the item
+// It is used to restore items that are pushed before
+func (tp *TransactionPool) PushItem(item *PoolItem) error {
+	_, err := tp.PushOrReplace(item)
+	return err
+}
+
+// PushOrReplace inserts the item and returns items that are removed by it
+// An account model based transaction replaces the transaction that has the same sequence of the same address only when its fee is higher,
+// so the user can replace the stuck transaction or cancel it by the transaction that has no effect
+// An UTXO model based transaction that spends UTXOs claimed by pushed transactions replaces them only when its fee is higher than all of them
+// When the pool is full, items that have the lowest fee are evicted only when the fee of the item is higher than them
+func (tp *TransactionPool) PushOrReplace(item *PoolItem) ([]*PoolItem, error) {
 	tp.Lock()
 	defer tp.Unlock()
 
-	TxHash := t.Hash()
-	if tp.txidMap[TxHash] {
-		return ErrExistTransaction
+	t := item.Transaction
+	TxHash := item.TxHash
+	if _, has := tp.itemMap[TxHash]; has {
+		return nil, ErrExistTransaction
 	}
 
-	item := &PoolItem{
-		Transaction: t,
-		TxHash:      TxHash,
-		Signatures:  sigs,
+	if Size, err := TransactionSize(t, item.Signatures); err != nil {
+		return nil, err
+	} else {
+		item.Size = Size
+	}
+	if tp.limits.MaxBytes > 0 && item.Size > tp.limits.MaxBytes {
+		return nil, ErrTxPoolFull
 	}
+	item.Fee = tp.feeCalculator(t)
+	item.order = tp.pushCount
+
+	var addr common.Address
+	var replaced *PoolItem
+	var conflicts []*PoolItem
 	if t.IsUTXO() {
-		tp.utxoQ.Push(t.Hash(), item)
-		tp.turnQ.Push(true)
+		conflicts = tp.conflictItems(t)
+		for _, v := range conflicts {
+			if !v.Fee.Less(item.Fee) {
+				return nil, ErrDoubleSpendInPool
+			}
+		}
 	} else {
 		tx, is := t.(AccountTransaction)
 		if !is {
-			return ErrNotAccountTransaction
+			return nil, ErrNotAccountTransaction
 		}
+		addr = tx.From()
+		if bucket, has := tp.bucketMap[addr]; has {
+			if old, has := bucket.seqMap[tx.Seq()]; has {
+				if !old.Fee.Less(item.Fee) {
+					return nil, ErrReplaceUnderpriced
+				}
+				replaced = old
+			} else if tp.limits.MaxPendingPerAddress > 0 && len(bucket.seqMap) >= tp.limits.MaxPendingPerAddress {
+				return nil, ErrTooManyPendingTransactions
+			}
+		}
+	}
+
+	removed := []*PoolItem{}
+	if replaced != nil {
+		tp.removeAccountItem(addr, replaced)
+		removed = append(removed, replaced)
+	}
+	for _, v := range conflicts {
+		tp.removeUTXOItem(v)
+		removed = append(removed, v)
+	}
+	for tp.isFull(item) {
+		target := tp.evictionTarget(item, addr)
+		if target == nil || !target.Fee.Less(item.Fee) {
+			// evicted items are returned to the pool because the item cannot be pushed
+			for _, v := range removed {
+				tp.insertItem(v)
+			}
+			return nil, ErrTxPoolFull
+		}
+		if target.Transaction.IsUTXO() {
+			tp.removeUTXOItem(target)
+		} else {
+			tp.removeAccountItem(target.Transaction.(AccountTransaction).From(), target)
+		}
+		removed = append(removed, target)
+	}
+	tp.insertItem(item)
+	tp.pushCount++
+	return removed, nil
+}
+
+// isFull returns that the item cannot be pushed without the eviction
+func (tp *TransactionPool) isFull(item *PoolItem) bool {
+	if tp.limits.MaxSize > 0 && len(tp.itemMap)+1 > tp.limits.MaxSize {
+		return true
+	}
+	if tp.limits.MaxBytes > 0 && tp.bytes+item.Size > tp.limits.MaxBytes {
+		return true
+	}
+	return false
+}
+
+// evictionTarget returns the item that has the lowest fee and the newest one of them, so it is the last one to be popped
+// The last sequence of each address is the candidate to keep the sequence of remained transactions continuous
+// The address of the pushing item is excluded because its transaction can be the next of the pushing item,
+// so the next candidate is one of children of the top in the heap
+func (tp *TransactionPool) evictionTarget(item *PoolItem, addr common.Address) *PoolItem {
+	h := tp.evictHeap.entryHeap
+	if len(h) == 0 {
+		return nil
+	}
+	if item.Transaction.IsUTXO() || h[0].item.Transaction.IsUTXO() || h[0].addr != addr {
+		return h[0].item
+	}
+	var target *PoolItem
+	for i := 1; i < 3 && i < len(h); i++ {
+		if target == nil || target.isPrior(h[i].item) {
+			target = h[i].item
+		}
+	}
+	return target
+}
+
+// conflictItems returns items that claim UTXOs spent by the transaction
+func (tp *TransactionPool) conflictItems(t transaction.Transaction) []*PoolItem {
+	list := []*PoolItem{}
+	utx, is := t.(UTXOTransaction)
+	if !is {
+		return list
+	}
+	checkMap := map[hash.Hash256]bool{}
+	for _, id := range utx.VinIDs() {
+		if TxHash, has := tp.utxoClaimMap[id]; has && !checkMap[TxHash] {
+			checkMap[TxHash] = true
+			list = append(list, tp.itemMap[TxHash])
+		}
+	}
+	return list
+}
+
+func (tp *TransactionPool) insertItem(item *PoolItem) {
+	t := item.Transaction
+	if t.IsUTXO() {
+		e := &poolEntry{
+			item: item,
+		}
+		heap.Push(tp.utxoHeap, e)
+		tp.utxoEntryMap[item.TxHash] = e
+		ee := &poolEntry{
+			item: item,
+		}
+		heap.Push(tp.evictHeap, ee)
+		tp.utxoEvictMap[item.TxHash] = ee
+		if utx, is := t.(UTXOTransaction); is {
+			for _, id := range utx.VinIDs() {
+				tp.utxoClaimMap[id] = item.TxHash
+			}
+		}
+	} else {
+		tx := t.(AccountTransaction)
 		addr := tx.From()
-		q, has := tp.bucketMap[addr]
+		bucket, has := tp.bucketMap[addr]
 		if !has {
-			q = queue.NewSortedQueue()
-			tp.bucketMap[addr] = q
+			bucket = newAccountBucket()
+			tp.bucketMap[addr] = bucket
 		}
-		q.Insert(item, tx.Seq())
-		tp.numberQ.Push(addr)
-		tp.turnQ.Push(false)
+		bucket.insert(item, tx.Seq())
+		tp.updateAddressEntry(addr)
 	}
-	tp.txidMap[TxHash] = true
-	return nil
+	tp.itemMap[item.TxHash] = item
+	tp.bytes += item.Size
 }
 
-// Remove deletes the target transaction from the queue
+func (tp *TransactionPool) removeUTXOItem(item *PoolItem) {
+	if e, has := tp.utxoEntryMap[item.TxHash]; has {
+		heap.Remove(tp.utxoHeap, e.index)
+		delete(tp.utxoEntryMap, item.TxHash)
+	}
+	if e, has := tp.utxoEvictMap[item.TxHash]; has {
+		heap.Remove(tp.evictHeap, e.index)
+		delete(tp.utxoEvictMap, item.TxHash)
+	}
+	if utx, is := item.Transaction.(UTXOTransaction); is {
+		for _, id := range utx.VinIDs() {
+			if TxHash, has := tp.utxoClaimMap[id]; has && TxHash == item.TxHash {
+				delete(tp.utxoClaimMap, id)
+			}
+		}
+	}
+	tp.deleteItem(item)
+}
+
+func (tp *TransactionPool) removeAccountItem(addr common.Address, item *PoolItem) {
+	if bucket, has := tp.bucketMap[addr]; has {
+		bucket.remove(item.Transaction.(AccountTransaction).Seq())
+		tp.updateAddressEntry(addr)
+	}
+	tp.deleteItem(item)
+}
+
+func (tp *TransactionPool) deleteItem(item *PoolItem) {
+	if _, has := tp.itemMap[item.TxHash]; has {
+		delete(tp.itemMap, item.TxHash)
+		tp.bytes -= item.Size
+	}
+}
+
+// updateAddressEntry updates the order of the address by the next transaction of it
+// and the eviction order of the address by the last transaction of it
+func (tp *TransactionPool) updateAddressEntry(addr common.Address) {
+	bucket, has := tp.bucketMap[addr]
+	if !has || bucket.queue.Size() == 0 {
+		delete(tp.bucketMap, addr)
+		if e, has := tp.addrEntryMap[addr]; has {
+			heap.Remove(tp.addrHeap, e.index)
+			delete(tp.addrEntryMap, addr)
+		}
+		if e, has := tp.addrEvictMap[addr]; has {
+			heap.Remove(tp.evictHeap, e.index)
+			delete(tp.addrEvictMap, addr)
+		}
+		return
+	}
+	last := bucket.seqMap[bucket.lastSeq]
+	if e, has := tp.addrEvictMap[addr]; has {
+		e.item = last
+		heap.Fix(tp.evictHeap, e.index)
+	} else {
+		e := &poolEntry{
+			addr: addr,
+			item: last,
+		}
+		heap.Push(tp.evictHeap, e)
+		tp.addrEvictMap[addr] = e
+	}
+	v, _ := bucket.queue.Peek()
+	item := v.(*PoolItem)
+	if e, has := tp.addrEntryMap[addr]; has {
+		e.item = item
+		heap.Fix(tp.addrHeap, e.index)
+	} else {
+		e := &poolEntry{
+			addr: addr,
+			item: item,
+		}
+		heap.Push(tp.addrHeap, e)
+		tp.addrEntryMap[addr] = e
+	}
+}
+
+// Remove deletes the target transaction from the queue and returns removed items
 // If it is an account model based transaction, it will be sorted by the sequence in the address
-func (tp *TransactionPool) Remove(TxHash hash.Hash256, t transaction.Transaction) {
+// If it is an UTXO model based transaction, items that claim the same UTXOs are removed together because they cannot be executed
+func (tp *TransactionPool) Remove(TxHash hash.Hash256, t transaction.Transaction) []*PoolItem {
 	tp.Lock()
 	defer tp.Unlock()
 
+	removed := []*PoolItem{}
 	if t.IsUTXO() {
-		if tp.utxoQ.Remove(TxHash) != nil {
-			tp.turnOutMap[true]++
-			delete(tp.txidMap, TxHash)
+		if item, has := tp.itemMap[TxHash]; has {
+			tp.removeUTXOItem(item)
+			removed = append(removed, item)
+		}
+		for _, item := range tp.conflictItems(t) {
+			tp.removeUTXOItem(item)
+			removed = append(removed, item)
 		}
 	} else {
 		tx := t.(AccountTransaction)
 		addr := tx.From()
-		if q, has := tp.bucketMap[addr]; has {
+		if bucket, has := tp.bucketMap[addr]; has {
 			for {
-				if q.Size() == 0 {
+				if bucket.queue.Size() == 0 {
 					break
 				}
-				v, _ := q.Peek()
+				v, _ := bucket.queue.Peek()
 				item := v.(*PoolItem)
 				if tx.Seq() < item.Transaction.(AccountTransaction).Seq() {
 					break
 				}
-				q.Pop()
-				delete(tp.txidMap, item.Transaction.Hash())
-				tp.turnOutMap[false]++
-				tp.numberOutMap[addr]++
-			}
-			if q.Size() == 0 {
-				delete(tp.bucketMap, addr)
+				bucket.pop()
+				tp.deleteItem(item)
+				removed = append(removed, item)
 			}
+			tp.updateAddressEntry(addr)
 		}
 	}
+	return removed
+}
+
+// RemoveExpired deletes items that cannot be included to blocks after the height and the timestamp and returns them
+func (tp *TransactionPool) RemoveExpired(Height uint32, Timestamp uint64) []*PoolItem {
+	tp.Lock()
+	defer tp.Unlock()
+
+	removed := []*PoolItem{}
+	for _, item := range tp.itemMap {
+		if transaction.IsExpired(item.Transaction, Height, Timestamp) {
+			removed = append(removed, item)
+		}
+	}
+	for _, item := range removed {
+		if item.Transaction.IsUTXO() {
+			tp.removeUTXOItem(item)
+		} else {
+			tp.removeAccountItem(item.Transaction.(AccountTransaction).From(), item)
+		}
+	}
+	return removed
+}
+
+// Clear removes all items and returns them by the order of the push
+// It is used to validate pooled transactions again when the state is changed without connecting blocks
+func (tp *TransactionPool) Clear() []*PoolItem {
+	tp.Lock()
+	defer tp.Unlock()
+
+	items := make([]*PoolItem, 0, len(tp.itemMap))
+	for _, item := range tp.itemMap {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].order < items[j].order
+	})
+	tp.bytes = 0
+	tp.utxoHeap = &entryHeap{}
+	tp.addrHeap = &entryHeap{}
+	tp.utxoEntryMap = map[hash.Hash256]*poolEntry{}
+	tp.addrEntryMap = map[common.Address]*poolEntry{}
+	tp.evictHeap = &evictionHeap{}
+	tp.utxoEvictMap = map[hash.Hash256]*poolEntry{}
+	tp.addrEvictMap = map[common.Address]*poolEntry{}
+	tp.itemMap = map[hash.Hash256]*PoolItem{}
+	tp.bucketMap = map[common.Address]*accountBucket{}
+	tp.utxoClaimMap = map[uint64]hash.Hash256{}
+	return items
+}
+
+// UnsafeRestore returns the popped item to the pool without mutex locking
+// The item keeps its order, so it is popped before items that are pushed after it
+func (tp *TransactionPool) UnsafeRestore(item *PoolItem) {
+	if _, has := tp.itemMap[item.TxHash]; has {
+		return
+	}
+	tp.insertItem(item)
 }
 
 // Pop returns and removes the proper transaction
@@ -152,82 +516,131 @@ func (tp *TransactionPool) Pop(SeqCache SeqCache) *PoolItem {
 }
 
 // UnsafePop returns and removes the proper transaction without mutex locking
+// It returns the transaction that has the highest fee among UTXO model based transactions and next transactions of addresses
 func (tp *TransactionPool) UnsafePop(SeqCache SeqCache) *PoolItem {
-	var bTurn bool
+	ignored := []*poolEntry{}
+	defer func() {
+		for _, e := range ignored {
+			heap.Push(tp.addrHeap, e)
+		}
+	}()
+
 	for {
-		turn := tp.turnQ.Pop()
-		if turn == nil {
+		var ue *poolEntry
+		var ae *poolEntry
+		if tp.utxoHeap.Len() > 0 {
+			ue = (*tp.utxoHeap)[0]
+		}
+		if tp.addrHeap.Len() > 0 {
+			ae = (*tp.addrHeap)[0]
+		}
+		if ue == nil && ae == nil {
 			return nil
 		}
-		bTurn = turn.(bool)
-		tout := tp.turnOutMap[bTurn]
-		if tout > 0 {
-			tp.turnOutMap[bTurn] = tout - 1
+		if ue != nil && (ae == nil || ue.item.isPrior(ae.item)) {
+			tp.removeUTXOItem(ue.item)
+			return ue.item
+		}
+
+		item := ae.item
+		lastSeq := SeqCache.Seq(ae.addr)
+		if item.Transaction.(AccountTransaction).Seq() != lastSeq+1 {
+			heap.Pop(tp.addrHeap)
+			ignored = append(ignored, ae)
 			continue
 		}
-		break
-	}
-	if bTurn {
-		item := tp.utxoQ.Pop().(*PoolItem)
-		delete(tp.txidMap, item.Transaction.Hash())
+		tp.bucketMap[ae.addr].pop()
+		tp.deleteItem(item)
+		tp.updateAddressEntry(ae.addr)
 		return item
-	} else {
-		remain := tp.numberQ.Size()
-		ignoreMap := map[common.Address]bool{}
-		for {
-			var addr common.Address
-			for {
-				if tp.numberQ.Size() == 0 {
-					return nil
-				}
-				addr = tp.numberQ.Pop().(common.Address)
-				remain--
-				nout := tp.numberOutMap[addr]
-				if nout > 0 {
-					nout--
-					if nout == 0 {
-						delete(tp.numberOutMap, addr)
-					} else {
-						tp.numberOutMap[addr] = nout
-					}
-					continue
-				}
-				break
-			}
-			if ignoreMap[addr] {
-				tp.numberQ.Push(addr)
-				if remain > 0 {
-					continue
-				} else {
-					return nil
-				}
-			}
-			q := tp.bucketMap[addr]
-			v, _ := q.Peek()
-			item := v.(*PoolItem)
-			lastSeq := SeqCache.Seq(addr)
-			if item.Transaction.(AccountTransaction).Seq() != lastSeq+1 {
-				ignoreMap[addr] = true
-				tp.numberQ.Push(addr)
-				if remain > 0 {
-					continue
-				} else {
-					return nil
-				}
-			}
-			q.Pop()
-			delete(tp.txidMap, item.Transaction.Hash())
-			if q.Size() == 0 {
-				delete(tp.bucketMap, addr)
-			}
-			return item
-		}
 	}
 }
 
+// TransactionSize returns the size of the transaction and signatures that are encoded in the block body
+// It includes the type of the transaction and the count of signatures
+func TransactionSize(t transaction.Transaction, sigs []common.Signature) (int64, error) {
+	var buffer bytes.Buffer
+	if _, err := t.WriteTo(&buffer); err != nil {
+		return 0, err
+	}
+	Size := int64(2 + buffer.Len())
+	for _, sig := range sigs {
+		Size += int64(len(sig))
+	}
+	return Size, nil
+}
+
 // PoolItem represents the item of the queue
 type PoolItem struct {
 	Transaction transaction.Transaction
 	TxHash      hash.Hash256
 	Signatures  []common.Signature
+	PushedAt    int64
+	Fee         *amount.Amount
+	Size        int64
+	order       uint64
+}
+
+// isPrior returns that the item should be popped before the other item
+func (item *PoolItem) isPrior(other *PoolItem) bool {
+	if item.Fee.Equal(other.Fee) {
+		return item.order < other.order
+	}
+	return other.Fee.Less(item.Fee)
+}
+
+// accountBucket is a sorted queue of account model based transactions of an address by the sequence
+type accountBucket struct {
+	queue   *queue.SortedQueue
+	seqMap  map[uint64]*PoolItem
+	lastSeq uint64
+}
+
+func newAccountBucket() *accountBucket {
+	return &accountBucket{
+		queue:  queue.NewSortedQueue(),
+		seqMap: map[uint64]*PoolItem{},
+	}
+}
+
+func (bucket *accountBucket) insert(item *PoolItem, seq uint64) {
+	bucket.queue.Insert(item, seq)
+	bucket.seqMap[seq] = item
+	if len(bucket.seqMap) == 1 || bucket.lastSeq < seq {
+		bucket.lastSeq = seq
+	}
+}
+
+func (bucket *accountBucket) pop() *PoolItem {
+	v, seq := bucket.queue.Pop()
+	delete(bucket.seqMap, seq)
+	return v.(*PoolItem)
+}
+
+// remove rebuilds the queue without the item of the sequence
+func (bucket *accountBucket) remove(seq uint64) {
+	if _, has := bucket.seqMap[seq]; !has {
+		return
+	}
+	items := []*PoolItem{}
+	seqs := []uint64{}
+	for bucket.queue.Size() > 0 {
+		v, s := bucket.queue.Pop()
+		if s != seq {
+			items = append(items, v.(*PoolItem))
+			seqs = append(seqs, s)
+		}
+	}
+	for i, item := range items {
+		bucket.queue.Insert(item, seqs[i])
+	}
+	delete(bucket.seqMap, seq)
+	if seq == bucket.lastSeq {
+		bucket.lastSeq = 0
+		for _, s := range seqs {
+			if bucket.lastSeq < s {
+				bucket.lastSeq = s
+			}
+		}
+	}
 }
@@ -2,10 +2,12 @@ package txpool
 
 import (
 	"encoding/hex"
+	"io"
 	"testing"
 	"time"
 
 	"github.com/fletaio/common/hash"
+	"github.com/fletaio/common/util"
 
 	"github.com/fletaio/common"
 	"github.com/fletaio/framework/log"
@@ -13,9 +15,6 @@ import (
 	"github.com/fletaio/core/amount"
 	"github.com/fletaio/core/key"
 	"github.com/fletaio/core/transaction"
-
-	"github.com/fletaio/extension/account_tx"
-	"github.com/fletaio/extension/utxo_tx"
 )
 
 type testCtx struct {
@@ -34,6 +33,100 @@ func (ctx *testCtx) updateSeq(tx AccountTransaction, seq uint64) {
 	ctx.m[tx.From()] = seq
 }
 
+// accountTestTx is an account model based transaction that has the priority fee
+type accountTestTx struct {
+	transaction.Base
+	Seq_  uint64
+	From_ common.Address
+	Fee   *amount.Amount
+}
+
+func (tx *accountTestTx) Seq() uint64                 { return tx.Seq_ }
+func (tx *accountTestTx) From() common.Address        { return tx.From_ }
+func (tx *accountTestTx) PriorityFee() *amount.Amount { return tx.Fee }
+func (tx *accountTestTx) IsUTXO() bool                { return false }
+func (tx *accountTestTx) Hash() hash.Hash256          { return hash.DoubleHashByWriterTo(tx) }
+
+func (tx *accountTestTx) WriteTo(w io.Writer) (int64, error) {
+	var wrote int64
+	if n, err := tx.Base.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteUint64(w, tx.Seq_); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.From_.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := tx.Fee.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	return wrote, nil
+}
+
+func (tx *accountTestTx) ReadFrom(r io.Reader) (int64, error) {
+	return 0, nil
+}
+
+func (tx *accountTestTx) MarshalJSON() ([]byte, error) {
+	return []byte("{}"), nil
+}
+
+// utxoTestTx is an UTXO model based transaction that has the priority fee
+type utxoTestTx struct {
+	transaction.Base
+	Vin []uint64
+	Fee *amount.Amount
+}
+
+func (tx *utxoTestTx) VinIDs() []uint64            { return tx.Vin }
+func (tx *utxoTestTx) PriorityFee() *amount.Amount { return tx.Fee }
+func (tx *utxoTestTx) IsUTXO() bool                { return true }
+func (tx *utxoTestTx) Hash() hash.Hash256          { return hash.DoubleHashByWriterTo(tx) }
+
+func (tx *utxoTestTx) WriteTo(w io.Writer) (int64, error) {
+	var wrote int64
+	if n, err := tx.Base.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	if n, err := util.WriteUint16(w, uint16(len(tx.Vin))); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	for _, id := range tx.Vin {
+		if n, err := util.WriteUint64(w, id); err != nil {
+			return wrote, err
+		} else {
+			wrote += n
+		}
+	}
+	if n, err := tx.Fee.WriteTo(w); err != nil {
+		return wrote, err
+	} else {
+		wrote += n
+	}
+	return wrote, nil
+}
+
+func (tx *utxoTestTx) ReadFrom(r io.Reader) (int64, error) {
+	return 0, nil
+}
+
+func (tx *utxoTestTx) MarshalJSON() ([]byte, error) {
+	return []byte("{}"), nil
+}
+
 func testSignature(tx transaction.Transaction) (transaction.Transaction, []common.Signature) {
 	MemKey := getMemKey("f6d94eb4131bda99277f3bc44fc498527ecd43177872a2b58ee7008225037a18")
 
@@ -45,7 +138,7 @@ func testSignature(tx transaction.Transaction) (transaction.Transaction, []commo
 }
 
 func testAddr(i int) common.Address {
-	return common.NewAddress(common.NewCoordinate(1, 1), common.NewCoordinate(1, 2), uint64(i))
+	return common.NewAddress(common.NewCoordinate(1, 1), uint64(i))
 }
 
 func getMemKey(h string) *key.MemoryKey {
@@ -62,57 +155,35 @@ func getMemKey(h string) *key.MemoryKey {
 
 }
 
-func testUTXOTx(i byte) transaction.Transaction {
-	coord := common.NewCoordinate(0, 0)
-
-	MemKey1 := getMemKey("0ad8e4cd0c22664e977b2a634e3a9ddf7f5985c6a7fa9ec389127bf272bbafbb")
-	MemKey2 := getMemKey(hash.Hash([]byte{i}).String())
-
-	t := &utxo_tx.OpenAccount{
-		Base: utxo_tx.Base{
-			Base: transaction.Base{
-				ChainCoord_: coord,
-				Timestamp_:  uint64(time.Now().UnixNano()),
-				Type_:       transaction.Type(255),
-			},
-			Vin: []*transaction.TxIn{
-				&transaction.TxIn{
-					Height: uint32(1),
-					Index:  uint16(1),
-					N:      uint16(1),
-				},
-			},
-		},
-		Vout: []*transaction.TxOut{
-			&transaction.TxOut{
-				Amount:     amount.NewCoinAmount(10, 10),
-				PublicHash: common.NewPublicHash(MemKey1.PublicKey()),
-			},
+func testUTXOTx(i int) transaction.Transaction {
+	return testUTXOTxWithFee(amount.NewCoinAmount(0, 0), uint64(i))
+}
+
+func testUTXOTxWithFee(Fee *amount.Amount, ids ...uint64) transaction.Transaction {
+	return &utxoTestTx{
+		Base: transaction.Base{
+			Timestamp_: uint64(time.Now().UnixNano()),
+			Type_:      transaction.Type(255),
 		},
-		KeyHash: common.NewPublicHash(MemKey2.PublicKey()),
+		Vin: ids,
+		Fee: Fee,
 	}
-
-	return t
 }
 
 func testAccTx(seq uint64, from common.Address) transaction.Transaction {
-	coord := common.NewCoordinate(0, 0)
-
-	MemKey := getMemKey("0ad8e4cd0c22664e977b2a634e3a9ddf7f5985c6a7fa9ec389127bf272bbafbb")
-
-	t := &account_tx.CreateAccount{
-		Base: account_tx.Base{
-			Base: transaction.Base{
-				ChainCoord_: coord,
-				Timestamp_:  uint64(time.Now().UnixNano()),
-				Type_:       transaction.Type(255),
-			},
-			Seq_:  seq,
-			From_: from,
+	return testAccTxWithFee(seq, from, amount.NewCoinAmount(0, 0))
+}
+
+func testAccTxWithFee(seq uint64, from common.Address, Fee *amount.Amount) transaction.Transaction {
+	return &accountTestTx{
+		Base: transaction.Base{
+			Timestamp_: uint64(time.Now().UnixNano()),
+			Type_:      transaction.Type(255),
 		},
-		KeyHash: common.NewPublicHash(MemKey.PublicKey()),
+		Seq_:  seq,
+		From_: from,
+		Fee:   Fee,
 	}
-	return t
 }
 
 func TestAccBasicPushPop2(t *testing.T) {
@@ -120,22 +191,22 @@ func TestAccBasicPushPop2(t *testing.T) {
 	MemKey := getMemKey(hash.Hash([]byte{1}).String())
 	log.Info(MemKey.PublicKey())
 	log.Info(common.NewPublicHash(MemKey.PublicKey()))
-	log.Info(common.NewAddress(common.NewCoordinate(1, 1), common.NewCoordinate(1, 2), uint64(1)))
+	log.Info(common.NewAddress(common.NewCoordinate(1, 1), uint64(1)))
 
 	MemKey = getMemKey(hash.Hash([]byte{2}).String())
 	log.Info(MemKey.PublicKey())
 	log.Info(common.NewPublicHash(MemKey.PublicKey()))
-	log.Info(common.NewAddress(common.NewCoordinate(1, 1), common.NewCoordinate(1, 2), uint64(1)))
+	log.Info(common.NewAddress(common.NewCoordinate(1, 1), uint64(1)))
 
 	MemKey = getMemKey(hash.Hash([]byte{3}).String())
 	log.Info(MemKey.PublicKey())
 	log.Info(common.NewPublicHash(MemKey.PublicKey()))
-	log.Info(common.NewAddress(common.NewCoordinate(1, 1), common.NewCoordinate(1, 2), uint64(1)))
+	log.Info(common.NewAddress(common.NewCoordinate(1, 1), uint64(1)))
 
 	MemKey = getMemKey(hash.Hash([]byte{4}).String())
 	log.Info(MemKey.PublicKey())
 	log.Info(common.NewPublicHash(MemKey.PublicKey()))
-	log.Info(common.NewAddress(common.NewCoordinate(1, 1), common.NewCoordinate(1, 2), uint64(1)))
+	log.Info(common.NewAddress(common.NewCoordinate(1, 1), uint64(1)))
 
 	time.Sleep(time.Second)
 }
@@ -184,7 +255,7 @@ func TestAccBasicPushPop(t *testing.T) {
 
 			for i := 0; i < tt.args.popCount; i++ {
 				tx := txPool.Pop(ctx).Transaction
-				atx := tx.(*account_tx.CreateAccount)
+				atx := tx.(*accountTestTx)
 				ctx.updateSeq(atx, atx.Seq_)
 			}
 
@@ -243,7 +314,7 @@ func TestAccPushPopIncreaseSeq(t *testing.T) {
 
 			for i := uint64(0); i < tt.args.popCount; i++ {
 				tx := txPool.Pop(ctx).Transaction
-				atx := tx.(*account_tx.CreateAccount)
+				atx := tx.(*accountTestTx)
 				ctx.updateSeq(atx, atx.Seq_)
 			}
 
@@ -300,7 +371,7 @@ func TestAccReversePushPop(t *testing.T) {
 
 			for i := uint64(0); i < tt.args.popCount; i++ {
 				tx := txPool.Pop(ctx).Transaction
-				atx := tx.(*account_tx.CreateAccount)
+				atx := tx.(*accountTestTx)
 				ctx.updateSeq(atx, atx.Seq_)
 			}
 
@@ -354,7 +425,7 @@ func TestAccPushPopRemove(t *testing.T) {
 
 			for i := uint64(0); i < tt.args.popCount; i++ {
 				tx := txPool.Pop(ctx).Transaction
-				atx := tx.(*account_tx.CreateAccount)
+				atx := tx.(*accountTestTx)
 				ctx.updateSeq(atx, atx.Seq_)
 			}
 
@@ -365,7 +436,7 @@ func TestAccPushPopRemove(t *testing.T) {
 				} else {
 					tx = txs[uint64(len(txs))-i-1]
 				}
-				txPool.Remove(tx)
+				txPool.Remove(tx.Hash(), tx)
 			}
 
 			length := uint64(txPool.Size())
@@ -405,7 +476,7 @@ func TestUTXOBasicPushPop(t *testing.T) {
 			}
 
 			for i := 0; i < tt.args.pushCount; i++ {
-				tx := testUTXOTx(byte(i))
+				tx := testUTXOTx(int(i))
 				txPool.Push(testSignature(tx))
 			}
 
@@ -463,7 +534,7 @@ func TestUTXOPushPopRemove(t *testing.T) {
 
 			txs := make([]transaction.Transaction, 0, tt.args.pushCount)
 			for i := uint64(1); i <= tt.args.pushCount; i++ {
-				tx := testUTXOTx(byte(i))
+				tx := testUTXOTx(int(i))
 				txs = append(txs, tx)
 				txPool.Push(testSignature(tx))
 			}
@@ -483,7 +554,7 @@ func TestUTXOPushPopRemove(t *testing.T) {
 				} else {
 					tx = txs[uint64(len(txs))-i-1]
 				}
-				txPool.Remove(tx)
+				txPool.Remove(tx.Hash(), tx)
 			}
 
 			length := uint64(txPool.Size())
@@ -496,3 +567,256 @@ func TestUTXOPushPopRemove(t *testing.T) {
 		})
 	}
 }
+
+func TestAccReplaceByFee(t *testing.T) {
+	tests := []struct {
+		name    string
+		fee     uint64
+		wantErr error
+	}{
+		{name: "lower fee", fee: 1, wantErr: ErrReplaceUnderpriced},
+		{name: "same fee", fee: 2, wantErr: ErrReplaceUnderpriced},
+		{name: "higher fee", fee: 3, wantErr: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txPool := NewTransactionPool()
+			addr := testAddr(1)
+
+			old := testAccTxWithFee(1, addr, amount.NewCoinAmount(2, 0))
+			if err := txPool.Push(testSignature(old)); err != nil {
+				t.Fatal(err)
+			}
+			tx := testAccTxWithFee(1, addr, amount.NewCoinAmount(tt.fee, 0))
+			removed, err := txPool.PushOrReplace(&PoolItem{Transaction: tx, TxHash: tx.Hash()})
+			if err != tt.wantErr {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			want := old
+			if tt.wantErr == nil {
+				want = tx
+				if len(removed) != 1 || removed[0].TxHash != old.Hash() {
+					t.Fatalf("removed = %v, want the replaced transaction", removed)
+				}
+			}
+			if txPool.Size() != 1 {
+				t.Fatalf("length = %v, want 1", txPool.Size())
+			}
+			ctx := &testCtx{
+				m: map[common.Address]uint64{},
+			}
+			if item := txPool.Pop(ctx); item == nil || item.TxHash != want.Hash() {
+				t.Fatalf("popped transaction is not the expected one")
+			}
+		})
+	}
+}
+
+func TestPushEvictionOrder(t *testing.T) {
+	addr := testAddr(1)
+	fee := func(v uint64) *amount.Amount {
+		return amount.NewCoinAmount(v, 0)
+	}
+	tests := []struct {
+		name    string
+		pushed  []transaction.Transaction
+		tx      transaction.Transaction
+		wantErr error
+		want    int
+	}{
+		{
+			name:    "not higher than the lowest fee",
+			pushed:  []transaction.Transaction{testUTXOTxWithFee(fee(1), 1), testUTXOTxWithFee(fee(2), 2)},
+			tx:      testUTXOTxWithFee(fee(1), 3),
+			wantErr: ErrTxPoolFull,
+			want:    -1,
+		},
+		{
+			name:   "lowest fee",
+			pushed: []transaction.Transaction{testUTXOTxWithFee(fee(2), 1), testUTXOTxWithFee(fee(1), 2)},
+			tx:     testUTXOTxWithFee(fee(3), 3),
+			want:   1,
+		},
+		{
+			name:   "newer one of the same fee",
+			pushed: []transaction.Transaction{testUTXOTxWithFee(fee(1), 1), testUTXOTxWithFee(fee(1), 2)},
+			tx:     testUTXOTxWithFee(fee(2), 3),
+			want:   1,
+		},
+		{
+			name:   "last sequence of the address",
+			pushed: []transaction.Transaction{testAccTxWithFee(1, addr, fee(1)), testAccTxWithFee(2, addr, fee(2))},
+			tx:     testUTXOTxWithFee(fee(3), 1),
+			want:   1,
+		},
+		{
+			name:   "address of the pushing transaction is excluded",
+			pushed: []transaction.Transaction{testAccTxWithFee(1, addr, fee(1)), testUTXOTxWithFee(fee(2), 1)},
+			tx:     testAccTxWithFee(2, addr, fee(3)),
+			want:   1,
+		},
+		{
+			name:    "only the address of the pushing transaction",
+			pushed:  []transaction.Transaction{testAccTxWithFee(1, addr, fee(1)), testAccTxWithFee(2, addr, fee(1))},
+			tx:      testAccTxWithFee(3, addr, fee(3)),
+			wantErr: ErrTxPoolFull,
+			want:    -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txPool := NewTransactionPool()
+			txPool.SetLimits(Limits{MaxSize: len(tt.pushed)})
+			for _, tx := range tt.pushed {
+				if err := txPool.Push(testSignature(tx)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			removed, err := txPool.PushOrReplace(&PoolItem{Transaction: tt.tx, TxHash: tt.tx.Hash()})
+			if err != tt.wantErr {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.want < 0 {
+				if len(removed) != 0 {
+					t.Fatalf("removed = %v, want nothing", removed)
+				}
+				for _, tx := range tt.pushed {
+					if !txPool.IsExist(tx.Hash()) {
+						t.Fatal("pushed transaction is removed by the rejected push")
+					}
+				}
+				return
+			}
+			if len(removed) != 1 || removed[0].TxHash != tt.pushed[tt.want].Hash() {
+				t.Fatalf("removed = %v, want the pushed transaction %v", removed, tt.want)
+			}
+			if txPool.Size() != len(tt.pushed) || !txPool.IsExist(tt.tx.Hash()) {
+				t.Fatal("pushing transaction is not inserted")
+			}
+		})
+	}
+}
+
+func TestUTXODoubleSpend(t *testing.T) {
+	tests := []struct {
+		name    string
+		fee     uint64
+		ids     []uint64
+		wantErr error
+	}{
+		{name: "same fee", fee: 2, ids: []uint64{2}, wantErr: ErrDoubleSpendInPool},
+		{name: "lower fee", fee: 1, ids: []uint64{2, 3}, wantErr: ErrDoubleSpendInPool},
+		{name: "higher fee", fee: 3, ids: []uint64{2, 3}, wantErr: nil},
+		{name: "no conflict", fee: 1, ids: []uint64{3}, wantErr: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txPool := NewTransactionPool()
+
+			old := testUTXOTxWithFee(amount.NewCoinAmount(2, 0), 1, 2)
+			if err := txPool.Push(testSignature(old)); err != nil {
+				t.Fatal(err)
+			}
+			tx := testUTXOTxWithFee(amount.NewCoinAmount(tt.fee, 0), tt.ids...)
+			removed, err := txPool.PushOrReplace(&PoolItem{Transaction: tx, TxHash: tx.Hash()})
+			if err != tt.wantErr {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if txPool.Size() != 1 || !txPool.IsExist(old.Hash()) {
+					t.Fatal("pushed transaction is removed by the rejected push")
+				}
+				return
+			}
+
+			conflict := false
+			for _, id := range tt.ids {
+				if id == 2 {
+					conflict = true
+				}
+			}
+			if conflict {
+				if len(removed) != 1 || removed[0].TxHash != old.Hash() || txPool.IsExist(old.Hash()) {
+					t.Fatal("double spent transaction is not evicted")
+				}
+				if txPool.Size() != 1 {
+					t.Fatalf("length = %v, want 1", txPool.Size())
+				}
+				// the utxo of the evicted transaction is not claimed anymore
+				other := testUTXOTxWithFee(amount.NewCoinAmount(0, 0), 1)
+				if err := txPool.Push(testSignature(other)); err != nil {
+					t.Fatal(err)
+				}
+			} else if len(removed) != 0 || txPool.Size() != 2 {
+				t.Fatal("transaction that has no conflict evicts others")
+			}
+		})
+	}
+}
+
+func TestPendingQueued(t *testing.T) {
+	A := testAddr(1)
+	B := testAddr(2)
+	utx := testUTXOTx(1)
+	pushed := []transaction.Transaction{
+		utx,
+		testAccTx(1, A),
+		testAccTx(2, A),
+		testAccTx(4, A),
+		testAccTx(3, B),
+		testAccTx(5, B),
+	}
+	txPool := NewTransactionPool()
+	for _, tx := range pushed {
+		if err := txPool.Push(testSignature(tx)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := &testCtx{
+		m: map[common.Address]uint64{
+			B: 2,
+		},
+	}
+
+	type want struct {
+		addr        common.Address
+		seq         uint64
+		expectedSeq uint64
+	}
+	check := func(name string, list []*ItemInfo, isPending bool, wants []want) {
+		if len(list) != len(wants) {
+			t.Fatalf("%v length = %v, want %v", name, len(list), len(wants))
+		}
+		for i, w := range wants {
+			info := list[i]
+			if info.IsPending != isPending || info.From != w.addr || info.Seq != w.seq || info.ExpectedSeq != w.expectedSeq {
+				t.Fatalf("%v %v = %v/%v/%v, want %v/%v", name, i, info.From, info.Seq, info.ExpectedSeq, w.seq, w.expectedSeq)
+			}
+		}
+	}
+
+	pending := txPool.Pending(ctx)
+	if len(pending) == 0 || !pending[0].IsUTXO || pending[0].TxHash != utx.Hash() {
+		t.Fatal("utxo model based transaction is not pending")
+	}
+	check("pending", pending[1:], true, []want{
+		{addr: A, seq: 1, expectedSeq: 1},
+		{addr: A, seq: 2, expectedSeq: 2},
+		{addr: B, seq: 3, expectedSeq: 3},
+	})
+	check("queued", txPool.Queued(ctx), false, []want{
+		{addr: A, seq: 4, expectedSeq: 3},
+		{addr: B, seq: 5, expectedSeq: 4},
+	})
+
+	// the transaction that fills the gap moves queued ones to pending
+	if err := txPool.Push(testSignature(testAccTx(3, A))); err != nil {
+		t.Fatal(err)
+	}
+	pendingA, queuedA := txPool.PendingByAddress(A, ctx)
+	if len(pendingA) != 4 || len(queuedA) != 0 {
+		t.Fatalf("pending = %v, queued = %v, want 4 and 0", len(pendingA), len(queuedA))
+	}
+}